@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"flag"
 	"log"
+	"runtime"
 	"time"
 
+	"parser/internal/botdetector"
 	"parser/internal/db"
 	"parser/internal/gen"
+	"parser/internal/ingest/schema"
 )
 
 func main() {
@@ -18,22 +20,28 @@ func main() {
 		year  = flag.Int("year", 0, "Year (e.g. 2025)")
 		pid   = flag.Int64("project-id", 0, "Project ID")
 
-		all               = flag.Bool("all", true, "Run all gen inserts")
-		mainF             = flag.Bool("main", false, "Only ln_genBotsMainStats")
-		srcF              = flag.Bool("by-source", false, "ln_genBotsMainStatsBySource")
-		mtdF              = flag.Bool("by-method", false, "ln_genBotsMainStatsByMethod")
-		verF              = flag.Bool("by-verification", false, "ln_genBotsMainStatsByVerification")
-		refF              = flag.Bool("by-refpage", false, "ln_genBotsMainStatsByRefPage")
-		tgtF              = flag.Bool("by-target", false, "ln_genBotsMainStatsByTarget")
-		flagByProtVersion = flag.Bool("by-protversion", false, "ln_genBotsMainStatsByProtVersion")
-		bySitemap         = flag.Bool("by-sitemap", false, "ln_genBotsMainStatsBySitemap")
+		bots    = flag.String("bots", "", "Bot rules file (.json or .yaml) used by normalize/verify; empty = built-in")
+		workers = flag.Int("workers", runtime.NumCPU(), "Aggregation workers (1 = single goroutine)")
+
+		all = flag.Bool("all", true, "Run all gen inserts")
 	)
+	// --<flag> po tabeli iz gen registra
+	tables := gen.Tables()
+	only := make([]*bool, len(tables))
+	for i, t := range tables {
+		only[i] = flag.Bool(t.Flag, false, t.Name)
+	}
 	flag.Parse()
 
 	if *pid == 0 || *month == 0 || *year == 0 {
 		log.Fatal("project-id, month, year are required")
 	}
 
+	// botName ključevi se kanonizuju po istim pravilima kao u parser-u
+	if err := botdetector.InitFromFile(*bots); err != nil {
+		log.Fatal(err)
+	}
+
 	dbh, err := db.Open()
 	if err != nil {
 		log.Fatal(err)
@@ -48,37 +56,54 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Hour)
 	defer cancel()
 
-	run := func(name string, fn func(context.Context, *sql.DB, gen.Params) error) {
-		log.Printf("[RUN] %s", name)
-		if err := fn(ctx, dbh, p); err != nil {
-			log.Fatalf("[FAIL] %s: %v", name, err)
+	// jedan prolaz kroz CSV za sve izabrane tabele
+	var (
+		run      []gen.Table
+		optional []string
+	)
+	for i, t := range tables {
+		if *all || *only[i] {
+			run = append(run, t)
+			if t.Optional {
+				optional = append(optional, t.Name)
+			}
 		}
-		log.Printf("[OK ] %s", name)
 	}
 
-	if *all || *mainF {
-		run("ln_genBotsMainStats", gen.InsertMain)
-	}
-	if *all || *srcF {
-		run("ln_genBotsMainStatsBySource", gen.InsertBySource)
-	}
-	if *all || *mtdF {
-		run("ln_genBotsMainStatsByMethod", gen.InsertByMethod)
-	}
-	if *all || *verF {
-		run("ln_genBotsMainStatsByVerification", gen.InsertByVerification)
-	}
-	if *all || *refF {
-		run("ln_genBotsMainStatsByRefPage", gen.InsertByRefPage)
-	}
-	if *all || *tgtF {
-		run("ln_genBotsMainStatsByTarget", gen.InsertByTarget)
+	// opcione (edge) tabele: preskoči one kojih nema u bazi (kao ingest)
+	if len(optional) > 0 {
+		_, missing, err := schema.HasTables(ctx, dbh, optional)
+		if err != nil {
+			log.Fatalf("[SCHEMA] error: %v", err)
+		}
+		if len(missing) > 0 {
+			log.Printf("[SKIP] tables missing %v — skipping their inserts (DDL: internal/ingest/schema/edge_tables.sql)", missing)
+			skip := make(map[string]bool, len(missing))
+			for _, name := range missing {
+				skip[name] = true
+			}
+			kept := run[:0]
+			for _, t := range run {
+				if !skip[t.Name] {
+					kept = append(kept, t)
+				}
+			}
+			run = kept
+		}
 	}
-	if *all || *flagByProtVersion {
-		run("ln_genBotsMainStatsByProtVersion", gen.InsertByProtVersion)
+	log.Printf("[RUN] aggregate %s (%d tables, workers=%d)", *csv, len(run), *workers)
+	agg, err := gen.Aggregate(ctx, *csv, run, *workers)
+	if err != nil {
+		log.Fatalf("[FAIL] aggregate: %v", err)
 	}
-	if *all || *bySitemap {
-		run("ln_genBotsMainStatsBySitemap", gen.InsertBySitemap)
+	p.Agg = agg
+
+	for _, t := range run {
+		log.Printf("[RUN] %s", t.Name)
+		if err := t.Insert(ctx, dbh, p); err != nil {
+			log.Fatalf("[FAIL] %s: %v", t.Name, err)
+		}
+		log.Printf("[OK ] %s", t.Name)
 	}
 
 	log.Printf("✅ geninsert complete")
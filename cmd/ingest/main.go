@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"parser/internal/botdetector"
 	"parser/internal/ingest/aggregators"
 	"parser/internal/ingest/config"
 	"parser/internal/ingest/csvx"
@@ -28,6 +29,7 @@ func main() {
 		flagDryRun    bool
 		flagCheck     bool
 		flagOnlyProj  bool
+		flagBots      string
 	)
 	flag.Int64Var(&flagProjectID, "project-id", 0, "Target project_id (default: pick an inactive placeholder automatically)")
 	flag.IntVar(&flagMonth, "month", 0, "Target month (1..12). If 0, autodetect from CSV")
@@ -36,8 +38,14 @@ func main() {
 	flag.BoolVar(&flagDryRun, "dry-run", false, "Do not write to DB (just aggregate and log)")
 	flag.BoolVar(&flagCheck, "check-schema", false, "Only check schema and exit")
 	flag.BoolVar(&flagOnlyProj, "only-project", false, "Only update logana_project (no inserts into other tables)")
+	flag.StringVar(&flagBots, "bots", "", "Bot rules file (.json or .yaml) for TTFB botName keys; empty = built-in")
 	flag.Parse()
 
+	// botName ključevi (TTFB) se kanonizuju po istim pravilima kao u geninsert-u
+	if err := botdetector.InitFromFile(flagBots); err != nil {
+		log.Fatal(err)
+	}
+
 	// Config + DB
 	cfg, err := config.Load()
 	if err != nil {
@@ -181,8 +189,9 @@ func main() {
 
 	// 4) Ako je -dry-run, ne diramo bazu – samo izveštaj i izlaz
 	if flagDryRun {
-		log.Printf("[DRY] filtered_rows=%d methods=%d respCodes=%d sitemap=%d aiBots=%d",
+		log.Printf("[DRY] filtered_rows=%d methods=%d respCodes=%d sitemap=%d aiBots=%d countries=%d colos=%d cache=%d ttfbBots=%d",
 			agg.FilteredRows, len(agg.MethodCounts), len(agg.StatusCounts), agg.SitemapCount, len(agg.AIBotCounts),
+			len(agg.CountryCounts), len(agg.ColoCounts), len(agg.CacheCounts), len(agg.Timing),
 		)
 		log.Printf("[DONE] Dry-run finished.")
 		return
@@ -266,6 +275,35 @@ func main() {
 		}
 	}
 
+	// 6d) edge INSERTs (country/colo/cache/TTFB) — ako CSV ima kolone i tabele postoje
+	if len(agg.CountryCounts)+len(agg.ColoCounts)+len(agg.CacheCounts)+len(agg.Timing) == 0 {
+		log.Printf("[SKIP] CSV has no edge columns — skipping edge inserts")
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+		defer cancel()
+		ok, missing, err := schema.HasTables(ctx, conn, writer.EdgeTables)
+		if err != nil {
+			log.Fatalf("[SCHEMA] error: %v", err)
+		}
+		if !ok {
+			log.Printf("[SKIP] edge tables missing %v — skipping edge inserts (DDL: internal/ingest/schema/edge_tables.sql)", missing)
+		} else {
+			err = writer.InsertEdge(ctx, conn, writer.EdgePayload{
+				ProjectID:  int(flagProjectID),
+				Month:      useMonth,
+				Year:       useYear,
+				CountryCnt: agg.CountryCounts,
+				ColoCnt:    agg.ColoCounts,
+				CacheCnt:   agg.CacheCounts,
+				Timing:     agg.Timing,
+			})
+			if err != nil {
+				log.Fatalf("insert edge error: %v", err)
+			}
+			log.Printf("[OK] edge inserts done")
+		}
+	}
+
 	// Ne diramo is_active ovde – ostaje 0, po dogovoru.
 	log.Printf("[DONE] Ingest finished at %s", time.Now().Format(time.RFC3339))
 }
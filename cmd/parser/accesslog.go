@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"parser/internal/accesslog"
+	"parser/internal/csvout"
+	"parser/internal/iox"
+	"parser/internal/mapper"
+)
+
+// ---------- STAGE accesslog: Apache/Nginx access log → normalized CSV ----------
+//
+// Zamena za jsonl+normalize kada klijent šalje sirove access logove.
+// Linija se parsira u Cloudflare imena polja i prolazi kroz isti
+// normalize spec (mapper), pa je izlaz identičan normalize izlazu i ide dalje
+// u enrich/verify/merge bez izmena.
+
+func runAccessLog(ctx context.Context, inPath, outPath, format, tz string, maxBadRatio float64, m *mapper.Mapper) (err error) {
+	if inPath == "" || outPath == "" {
+		return fmt.Errorf("accesslog: --in and --out are required")
+	}
+	if inPath == outPath && !iox.IsStdio(inPath) {
+		return fmt.Errorf("accesslog: input and output paths must differ (got %q)", inPath)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return fmt.Errorf("accesslog: --log-tz: %w", err)
+	}
+	p, err := accesslog.Compile(format, loc)
+	if err != nil {
+		return err
+	}
+
+	in, err := iox.OpenAuto(inPath)
+	if err != nil {
+		return fmt.Errorf("open input: %w", err)
+	}
+	defer in.Close()
+
+	out, err := iox.CreateAuto(outPath)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
+
+	writer := csvout.NewFor(out, outPath)
+	if err := writer.WriteHeader(m.Header()); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	strictDone, err := validateOutput("accesslog", writer, m.Header(), outPath)
+	if err != nil {
+		return err
+	}
+	defer strictDone()
+
+	var lineNo, rowsOut, bad, empty int64
+	start := time.Now()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	br := bufio.NewReaderSize(in, 1<<20)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			log.Printf("accesslog progress: lines=%d out=%d bad=%d", lineNo, rowsOut, bad)
+		default:
+		}
+
+		line, rerr := br.ReadString('\n')
+		if len(line) > 0 {
+			lineNo++
+			src, err := p.Parse(line)
+			switch {
+			case err != nil:
+				bad++
+				if bad <= 5 {
+					log.Printf("accesslog: line %d: %v", lineNo, err)
+				}
+			case src == nil:
+				empty++
+			default:
+				if err := writer.WriteRow(m.Map(src)); err != nil {
+					return fmt.Errorf("write row: %w", err)
+				}
+				rowsOut++
+			}
+		}
+		if rerr != nil {
+			if rerr != io.EOF {
+				return fmt.Errorf("read input after line %d: %w", lineNo, rerr)
+			}
+			break
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	if err := strictDone(); err != nil {
+		return err
+	}
+	log.Printf("accesslog done. lines=%d out=%d bad=%d empty=%d time=%s", lineNo, rowsOut, bad, empty, time.Since(start))
+	if rowsOut+bad > 0 {
+		if ratio := float64(bad) / float64(rowsOut+bad); maxBadRatio > 0 && ratio > maxBadRatio {
+			return fmt.Errorf("accesslog: bad line ratio %.4f exceeds max %.4f — wrong --log-format?", ratio, maxBadRatio)
+		}
+	}
+	if rowsOut == 0 {
+		log.Printf("accesslog: WARNING: produced 0 rows — check --log-format")
+	}
+	return nil
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,11 +18,13 @@ import (
 	"parser/internal/botdetector"
 	"parser/internal/csvin"
 	"parser/internal/csvout"
+	"parser/internal/dnscache"
 	"parser/internal/enrich"
 	"parser/internal/iox"
 	"parser/internal/jsonl"
 	"parser/internal/mapper"
 	"parser/internal/schema"
+	"parser/internal/source"
 	"parser/internal/verifier"
 )
 
@@ -26,19 +32,43 @@ var version = "v1.4"
 
 func main() {
 	// Common I/O + stage
-	inPath := flag.String("in", "", "Input file path")
-	outPath := flag.String("out", "", "Output file path")
-	stage := flag.String("stage", "normalize", "Stage: jsonl | normalize | enrich | verify | merge | aibots")
+	inPath := flag.String("in", "", "Input file path (- = stdin)")
+	outPath := flag.String("out", "", "Output file path (- = stdout)")
+	stage := flag.String("stage", "normalize", "Stage: jsonl | accesslog | normalize | enrich | verify | merge | aibots | all (whole pipeline in one process)")
 
 	// JSONL acceleration flags
 	jsonlWorkers := flag.Int("jsonl-workers", 8, "Number of workers for jsonl stage")
 	jsonlTempDir := flag.String("jsonl-temp", "", "Temp dir for jsonl stage (default: system temp)")
 	jsonlBuf := flag.Int("jsonl-buf", 8192, "Buffered jobs (lines) for jsonl stage")
+	jsonlOrdered := flag.Bool("jsonl-ordered", false, "jsonl stage: keep input line order in the output CSV")
+	jsonlRejects := flag.String("jsonl-rejects", "", "jsonl/all stage: write invalid lines (line number, parse error, raw) to this CSV")
+	maxBadRatio := flag.Float64("max-bad-ratio", 0, "jsonl/accesslog/all stage: fail when bad/(good+bad) lines exceed this ratio (0 = never)")
+	strict := flag.String("strict", "off", "Typed validation of output rows against schema kinds (int/bool/time): off | coerce (invalid value → empty) | reject (row → --strict-rejects) | fail (stop the stage)")
+	strictRejectsPath := flag.String("strict-rejects", "", "--strict=reject: CSV for rejected rows (default: <out>.rejects.csv)")
+	jsonlColumns := flag.String("jsonl-columns", "", "jsonl stage: fixed output columns (comma-separated, or \"mapper\" for the fields normalize reads); empty = sorted union of all keys")
+
+	// Source mapping + normalize spec
+	sourceFormat := flag.String("source-format", "auto", "normalize/all stage: input profile: auto (detect from header) | cloudflare | cloudfront | akamai | fastly | path to a .yaml/.json profile")
+	mapSpec := flag.String("map-spec", "", "normalize/accesslog/all stage: field mapping spec (.yaml/.json); empty = built-in default spec")
+
+	// Access log (Apache/Nginx) flags
+	logFormat := flag.String("log-format", "combined", "accesslog stage: common | combined | nginx | custom nginx log_format ($vars) or Apache LogFormat (%directives)")
+	logTZ := flag.String("log-tz", "UTC", "accesslog stage: time zone for output timestamps (IANA name); offsets in the log are honored")
 
 	// Verify / Merge flags
-	botsPath := flag.String("bots", "", "Bot rules file (.json or .yaml)")
-	workers := flag.Int("workers", 15, "Number of parallel DNS lookup workers (verify stage)")
+	botsPath := flag.String("bots", "", "Bot rules file (.json or .yaml); replaces the built-in rules, empty = built-in")
+	workers := flag.Int("workers", 15, "Number of parallel workers: DNS lookups (verify stage), row workers (normalize/enrich stages)")
 	uaPtrVerify := flag.Bool("ua-ptr-verify", false, "Mark verified=1 when UA and PTR share same base domain (heuristic)")
+	trustSource := flag.Bool("trust-source-verified", false, "Merge stage: keep verified=1 rows without verify_reason (source verdict, e.g. Cloudflare VerifiedBotCategory) instead of the verified.csv verdict")
+	verifiedPath := flag.String("verified", "", "Merge stage: verified CSV from the verify stage (--in is the enriched CSV)")
+	spoofReport := flag.String("spoof-report", "", "Merge stage: write per-bot spoofed-hit report CSV to this path")
+	dnsServer := flag.String("dns-server", "", "DNS server for verify stage (host[:port]); default: system resolver")
+	dnsFake := flag.String("dns-fake", "", "Offline resolver file (.json/.yaml with ptr/hosts tables) for verify stage")
+	dnsCachePath := flag.String("dns-cache", "", "Persistent DNS verification cache file (verify stage); empty = disabled")
+	dnsCacheTTL := flag.Duration("dns-cache-ttl", 30*24*time.Hour, "Cache TTL for verified=1 results")
+	dnsCacheNegTTL := flag.Duration("dns-cache-neg-ttl", 24*time.Hour, "Cache TTL for verified=0 results")
+	dnsCacheBypass := flag.Bool("dns-cache-bypass", false, "Ignore cached entries (fresh lookups are still written to the cache)")
+	dnsCachePrune := flag.Bool("dns-cache-prune", false, "Delete expired cache entries before verify")
 
 	// Mapper fallback for http/https when ClientRequestScheme is missing
 	defaultScheme := flag.String("default-scheme", "https", "Fallback scheme when ClientRequestScheme is missing (http or https)")
@@ -49,17 +79,36 @@ func main() {
 	// Plumb default scheme to mapper (used in protocol + absolute URL construction)
 	mapper.SetDefaultScheme(strings.ToLower(strings.TrimSpace(*defaultScheme)))
 
+	mode, err := schema.ParseMode(*strict)
+	if err != nil {
+		log.Fatal(err)
+	}
+	strictMode, strictRejects = mode, *strictRejectsPath
+
 	if *showPlan {
 		fmt.Printf("==== Parser %s Execution Plan ====\n", version)
 		fmt.Printf("Stage              : %s\n", *stage)
 		fmt.Printf("Input              : %s\n", *inPath)
 		fmt.Printf("Output             : %s\n", *outPath)
+		fmt.Printf("Verified (merge)   : %s\n", *verifiedPath)
 		fmt.Printf("Bots rules         : %s\n", *botsPath)
-		fmt.Printf("Workers (DNS)      : %d\n", *workers)
+		fmt.Printf("Workers            : %d\n", *workers)
 		fmt.Printf("UA↔PTR verify      : %v\n", *uaPtrVerify)
+		fmt.Printf("Trust source verif.: %v\n", *trustSource)
+		fmt.Printf("Spoof report       : %s\n", *spoofReport)
+		fmt.Printf("DNS server         : %s\n", *dnsServer)
+		fmt.Printf("DNS fake file      : %s\n", *dnsFake)
+		fmt.Printf("DNS cache          : %s (ttl=%s neg=%s bypass=%v prune=%v)\n", *dnsCachePath, *dnsCacheTTL, *dnsCacheNegTTL, *dnsCacheBypass, *dnsCachePrune)
 		fmt.Printf("JSONL workers      : %d\n", *jsonlWorkers)
 		fmt.Printf("JSONL tempdir      : %s\n", *jsonlTempDir)
 		fmt.Printf("JSONL bufsize      : %d\n", *jsonlBuf)
+		fmt.Printf("JSONL columns      : %s\n", *jsonlColumns)
+		fmt.Printf("JSONL ordered      : %v\n", *jsonlOrdered)
+		fmt.Printf("JSONL rejects      : %s (max bad ratio %g)\n", *jsonlRejects, *maxBadRatio)
+		fmt.Printf("Source format      : %s\n", *sourceFormat)
+		fmt.Printf("Map spec           : %s\n", *mapSpec)
+		fmt.Printf("Log format (access): %s (tz=%s)\n", *logFormat, *logTZ)
+		fmt.Printf("Strict             : %s (rejects=%s)\n", strictMode, *strictRejectsPath)
 		fmt.Printf("Default scheme     : %s\n", *defaultScheme)
 		return
 	}
@@ -71,79 +120,217 @@ func main() {
 
 	ctx := context.Background()
 
+	// normalize spec: ugrađeni ili --map-spec
+	specMapper := mapper.Default()
+	if *mapSpec != "" {
+		m, err := mapper.LoadSpec(*mapSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		specMapper = m
+	}
+
 	switch *stage {
 	case "jsonl":
-		err := jsonl.ConvertJSONLToCSVConcurrent(
+		cols, err := parseColumnList(*jsonlColumns, specMapper)
+		if err != nil {
+			log.Fatalf("jsonl stage: %v", err)
+		}
+		st, err := jsonl.ConvertJSONLToCSVConcurrent(
 			*inPath,
 			*outPath,
 			jsonl.Options{
-				Workers:  *jsonlWorkers,
-				TempDir:  *jsonlTempDir,
-				BufLines: *jsonlBuf,
+				Workers:     *jsonlWorkers,
+				TempDir:     *jsonlTempDir,
+				BufLines:    *jsonlBuf,
+				Columns:     cols,
+				Ordered:     *jsonlOrdered,
+				RejectsPath: *jsonlRejects,
+				MaxBadRatio: *maxBadRatio,
 			},
 		)
+		log.Printf("jsonl lines: good=%d bad=%d empty=%d bad_ratio=%.4f", st.Good, st.Bad, st.Empty, st.BadRatio())
 		if err != nil {
 			log.Fatalf("jsonl stage: %v", err)
 		}
 		log.Println("✅ JSONL → CSV conversion complete")
 
+	case "accesslog":
+		if err := botdetector.InitFromFile(*botsPath); err != nil {
+			log.Fatal(err)
+		}
+		if err := runAccessLog(ctx, *inPath, *outPath, *logFormat, *logTZ, *maxBadRatio, specMapper); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("✅ Access log → normalized CSV complete")
+
 	case "normalize":
-		if err := runNormalize(ctx, *inPath, *outPath); err != nil {
+		if err := botdetector.InitFromFile(*botsPath); err != nil {
+			log.Fatal(err)
+		}
+		if err := runNormalize(ctx, *inPath, *outPath, *sourceFormat, specMapper, *workers); err != nil {
 			log.Fatal(err)
 		}
 		log.Println("✅ Normalization complete")
 
 	case "enrich":
-		if err := runEnrich(ctx, *inPath, *outPath); err != nil {
+		if err := runEnrich(ctx, *inPath, *outPath, *workers); err != nil {
 			log.Fatal(err)
 		}
 		log.Println("✅ Enrichment complete")
 
 	case "verify":
-		if err := botdetector.InitFromFile(*botsPath); err != nil && *botsPath != "" {
-			log.Printf("warning: bot rules load failed: %v (using defaults)", err)
+		if err := botdetector.InitFromFile(*botsPath); err != nil {
+			log.Fatal(err)
 		}
-		if err := runVerify(ctx, *inPath, *outPath, *workers); err != nil {
+		res, err := verifier.OpenResolver(*dnsServer, *dnsFake)
+		if err != nil {
+			log.Fatal(err)
+		}
+		vc := verifyCacheOpts{
+			Path:   *dnsCachePath,
+			Bypass: *dnsCacheBypass,
+			Prune:  *dnsCachePrune,
+			TTL:    dnscache.Options{PositiveTTL: *dnsCacheTTL, NegativeTTL: *dnsCacheNegTTL},
+		}
+		if err := runVerify(ctx, res, vc, *inPath, *outPath, *workers); err != nil {
 			log.Fatal(err)
 		}
 		log.Println("✅ DNS verification complete")
 
 	case "merge":
-		if err := runMerge(ctx, "final.csv", "verified.csv", *outPath, *uaPtrVerify); err != nil {
+		if err := botdetector.InitFromFile(*botsPath); err != nil {
+			log.Fatal(err)
+		}
+		if err := runMerge(ctx, *inPath, *verifiedPath, *outPath, *uaPtrVerify, *trustSource, *spoofReport); err != nil {
 			log.Fatal(err)
 		}
 		log.Println("✅ Merge complete")
 
 	case "aibots":
+		if err := botdetector.InitFromFile(*botsPath); err != nil {
+			log.Fatal(err)
+		}
 		if err := runAIBots(ctx, *inPath, *outPath); err != nil {
 			log.Fatal(err)
 		}
 		log.Println("✅ AI-bots tagging complete")
 
+	case "all":
+		if err := botdetector.InitFromFile(*botsPath); err != nil {
+			log.Fatal(err)
+		}
+		res, err := verifier.OpenResolver(*dnsServer, *dnsFake)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = runPipeline(ctx, pipelineConfig{
+			InPath:   *inPath,
+			OutPath:  *outPath,
+			TempDir:  *jsonlTempDir,
+			Workers:  *jsonlWorkers,
+			Format:   *sourceFormat,
+			Mapper:   specMapper,
+			Resolver: res,
+
+			RejectsPath: *jsonlRejects,
+			MaxBadRatio: *maxBadRatio,
+			Cache: verifyCacheOpts{
+				Path:   *dnsCachePath,
+				Bypass: *dnsCacheBypass,
+				Prune:  *dnsCachePrune,
+				TTL:    dnscache.Options{PositiveTTL: *dnsCacheTTL, NegativeTTL: *dnsCacheNegTTL},
+			},
+			DNSWorkers:  *workers,
+			UAPtrVerify: *uaPtrVerify,
+			TrustSource: *trustSource,
+			SpoofReport: *spoofReport,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("✅ Pipeline (jsonl → aibots) complete")
+
 	default:
 		log.Fatalf("unknown stage: %s", *stage)
 	}
 }
 
+// parseColumnList: "--jsonl-columns" → lista kolona. "mapper" (ili
+// "cloudflare") znači tačno ona polja koja normalize spec (m) čita;
+// ime drugog source profila (fastly, akamai …) daje polja tog profila.
+func parseColumnList(s string, m *mapper.Mapper) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(s, "mapper") || strings.EqualFold(s, source.Cloudflare.Name) {
+		return m.SourceFields(), nil
+	}
+	if !strings.Contains(s, ",") {
+		if p, ok := source.Lookup(s); ok {
+			return p.SourceFields(), nil
+		}
+		if ext := strings.ToLower(filepath.Ext(s)); ext == ".yaml" || ext == ".yml" || ext == ".json" {
+			p, err := source.LoadFile(s)
+			if err != nil {
+				return nil, err
+			}
+			return p.SourceFields(), nil
+		}
+	}
+	var cols []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols, nil
+}
+
 // ---------- STAGE 1: normalize ----------
-func runNormalize(ctx context.Context, inPath, outPath string) error {
+func runNormalize(ctx context.Context, inPath, outPath, sourceFormat string, m *mapper.Mapper, workers int) (err error) {
 	if inPath == "" || outPath == "" {
 		return fmt.Errorf("normalize: --in and --out are required")
 	}
-	if inPath == outPath {
+	if inPath == outPath && !iox.IsStdio(inPath) {
 		return fmt.Errorf("normalize: input and output paths must differ (got %q)", inPath)
 	}
 
-	in, err := iox.OpenAuto(inPath)
-	if err != nil {
-		return fmt.Errorf("open input: %w", err)
-	}
-	defer in.Close()
-
-	reader := csvin.New(in, csvin.Options{Comma: ',', TrimSpace: true})
-	header, _, err := reader.Header()
-	if err != nil {
-		return fmt.Errorf("read header: %w", err)
+	// CSV (jsonl izlaz), Parquet ili W3C extended log (CloudFront, "#Fields:" header)
+	var (
+		read   func() ([]string, error)
+		header []string
+		cr     *csvin.Reader // nil za W3C (nema loših redova, samo I/O greške)
+	)
+	if iox.IsParquet(inPath) {
+		pr, err := csvin.Open(inPath, csvin.Options{})
+		if err != nil {
+			return fmt.Errorf("open input: %w", err)
+		}
+		defer pr.Close()
+		header, _, _ = pr.Header()
+		read, cr = pr.Read, pr
+	} else {
+		in, err := iox.OpenAuto(inPath)
+		if err != nil {
+			return fmt.Errorf("open input: %w", err)
+		}
+		defer in.Close()
+
+		br := bufio.NewReaderSize(in, 1<<20)
+		if source.IsW3C(br) {
+			wr := source.NewW3CReader(br)
+			header, err = wr.Header()
+			read = wr.Read
+		} else {
+			cr = csvin.New(br, csvin.Options{})
+			header, _, err = cr.Header()
+			read = cr.Read
+		}
+		if err != nil {
+			return fmt.Errorf("read header: %w", err)
+		}
 	}
 	if len(header) == 0 {
 		return fmt.Errorf("normalize: input has no header")
@@ -155,193 +342,211 @@ func runNormalize(ctx context.Context, inPath, outPath string) error {
 		return header
 	}())
 
+	prof, detected, err := source.Resolve(sourceFormat, header)
+	if err != nil {
+		return fmt.Errorf("normalize: %w", err)
+	}
+	switch {
+	case detected:
+		log.Printf("normalize: source format %s (detected from header)", prof.Name)
+	case strings.EqualFold(strings.TrimSpace(sourceFormat), "auto") || sourceFormat == "":
+		log.Printf("normalize: WARNING: could not detect source format from header, assuming %s", prof.Name)
+	default:
+		log.Printf("normalize: source format %s", prof.Name)
+	}
+
 	out, err := iox.CreateAuto(outPath)
 	if err != nil {
 		return fmt.Errorf("create output: %w", err)
 	}
-	defer out.Close()
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
 
-	writer := csvout.New(out)
-	if err := writer.WriteHeader(schema.BaseHeader()); err != nil {
+	writer := csvout.NewFor(out, outPath)
+	if err := writer.WriteHeader(m.Header()); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
+	strictDone, err := validateOutput("normalize", writer, m.Header(), outPath)
+	if err != nil {
+		return err
+	}
+	defer strictDone()
 
-	var rowsIn, rowsOut, bad int64
+	// imena polja se razrešavaju jednom; radnik puni vals/out bez mapa
+	bind := prof.Bind(header, m.SourceFields())
+	nf := len(m.SourceFields())
 	start := time.Now()
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			log.Printf("normalize progress: in=%d out=%d bad=%d", rowsIn, rowsOut, bad)
-		default:
-			row, err := reader.Next()
-			if err != nil {
-				if err == io.EOF {
-					goto DONE
-				}
-				bad++
-				continue
-			}
-			rowsIn++
-
-			outRow := mapper.MapToCSV(row)
-			if err := writer.WriteRow(outRow); err != nil {
-				return fmt.Errorf("write row: %w", err)
-			}
-			rowsOut++
+	st, err := processRows(ctx, "normalize", workers, read, func() rowFunc {
+		vals := make([]string, nf)
+		return func(rec, out []string) []string {
+			return m.MapValues(bind.Values(rec, vals), out)
 		}
+	}, writer.WriteRow)
+	if cr != nil {
+		st.Bad = cr.Stats().Bad
+	}
+	if err != nil {
+		return fmt.Errorf("normalize: %w", err)
 	}
 
-DONE:
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("flush: %w", err)
 	}
-	log.Printf("normalize done. in=%d out=%d bad=%d time=%s", rowsIn, rowsOut, bad, time.Since(start))
-	if rowsOut == 0 {
+	if err := strictDone(); err != nil {
+		return err
+	}
+	log.Printf("normalize done. in=%d out=%d bad=%d workers=%d time=%s", st.In, st.Out, st.Bad, workers, time.Since(start))
+	if st.Out == 0 {
 		log.Printf("normalize: WARNING: produced 0 rows — check input columns (e.g. ClientIP)")
 	}
 	return nil
 }
 
 // ---------- STAGE 2: enrich ----------
-func runEnrich(ctx context.Context, inPath, outPath string) error {
-	in, err := iox.OpenAuto(inPath)
+func runEnrich(ctx context.Context, inPath, outPath string, workers int) (err error) {
+	reader, err := csvin.Open(inPath, csvin.Options{})
 	if err != nil {
 		return fmt.Errorf("open input: %w", err)
 	}
-	defer in.Close()
+	defer reader.Close()
 
 	out, err := iox.CreateAuto(outPath)
 	if err != nil {
 		return fmt.Errorf("create output: %w", err)
 	}
-	defer out.Close()
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
 
-	reader := csvin.New(in, csvin.Options{Comma: ',', TrimSpace: true})
-	_, _, err = reader.Header() // skip input header
+	inHeader, _, err := reader.Header()
 	if err != nil {
 		return fmt.Errorf("read header: %w", err)
 	}
+	header := outputHeader(inHeader) // BaseColumns + dodatne kolone iz normalize speca
 
-	writer := csvout.New(out)
-	if err := writer.WriteHeader(schema.BaseHeader()); err != nil {
+	writer := csvout.NewFor(out, outPath)
+	if err := writer.WriteHeader(header); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
+	strictDone, err := validateOutput("enrich", writer, header, outPath)
+	if err != nil {
+		return err
+	}
+	defer strictDone()
 
-	var rowsIn, rowsOut, bad int64
+	proj := newProjection(reader, header)
+	enr := newEnricher(header)
 	start := time.Now()
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			log.Printf("enrich progress: in=%d out=%d bad=%d", rowsIn, rowsOut, bad)
-		default:
-			row, err := reader.Next()
-			if err != nil {
-				if err == io.EOF {
-					goto DONE
-				}
-				bad++
-				continue
-			}
-			rowsIn++
-
-			// 1) target classification
-			url := row["target"]
-			if url == "" {
-				url = row["referring_page"]
-			}
-			row["target"] = enrich.ResourceTypeFromURL(url)
-
-			// 2) referrer => "Direct Hit" if empty but referring_page is set
-			ref := strings.TrimSpace(row["referrer"])
-			refpg := strings.TrimSpace(row["referring_page"])
-			if (ref == "" || ref == "-") && refpg != "" && refpg != "-" {
-				row["referrer"] = "Direct Hit"
-			}
-
-			outRow := make([]string, len(schema.BaseColumns))
-			for i, c := range schema.BaseColumns {
-				outRow[i] = row[c.Name]
-			}
-			if err := writer.WriteRow(outRow); err != nil {
-				return fmt.Errorf("write row: %w", err)
-			}
-			rowsOut++
+	st, err := processRows(ctx, "enrich", workers, reader.Read, func() rowFunc {
+		return func(rec, out []string) []string {
+			out = proj.fill(rec, out)
+			enr.apply(out)
+			return out
 		}
+	}, writer.WriteRow)
+	st.Bad = reader.Stats().Bad
+	if err != nil {
+		return fmt.Errorf("enrich: %w", err)
 	}
 
-DONE:
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("flush: %w", err)
 	}
-	log.Printf("enrich done. in=%d out=%d bad=%d time=%s", rowsIn, rowsOut, bad, time.Since(start))
+	if err := strictDone(); err != nil {
+		return err
+	}
+	log.Printf("enrich done. in=%d out=%d bad=%d workers=%d time=%s", st.In, st.Out, st.Bad, workers, time.Since(start))
 	return nil
 }
 
+// enricher: per-row logika enrich faze nad redom u header redosledu
+// (deljena sa --stage all). Kolone koje header nema se preskaču.
+type enricher struct {
+	target, referrer, refPage int
+}
+
+func newEnricher(header []string) enricher {
+	return enricher{
+		target:   colIndex(header, "target"),
+		referrer: colIndex(header, "referrer"),
+		refPage:  colIndex(header, "referring_page"),
+	}
+}
+
+func (e enricher) apply(row []string) {
+	// 1) target classification
+	url := cell(row, e.target)
+	if url == "" {
+		url = cell(row, e.refPage)
+	}
+	setCell(row, e.target, enrich.ResourceTypeFromURL(url))
+
+	// 2) referrer => "Direct Hit" if empty but referring_page is set
+	ref := strings.TrimSpace(cell(row, e.referrer))
+	refpg := strings.TrimSpace(cell(row, e.refPage))
+	if (ref == "" || ref == "-") && refpg != "" && refpg != "-" {
+		setCell(row, e.referrer, "Direct Hit")
+	}
+}
+
 // ---------- STAGE 3: verify ----------
-func runVerify(ctx context.Context, inPath, outPath string, workers int) error {
+type verifyCacheOpts struct {
+	Path   string // "" => bez keša
+	Bypass bool
+	Prune  bool
+	TTL    dnscache.Options
+}
+
+func runVerify(ctx context.Context, res verifier.Resolver, vc verifyCacheOpts, inPath, outPath string, workers int) error {
 	log.Printf("verify stage: reading unique IPs from %s", inPath)
 
-	in, err := iox.OpenAuto(inPath)
+	reader, err := csvin.Open(inPath, csvin.Options{})
 	if err != nil {
 		return fmt.Errorf("open input: %w", err)
 	}
-	defer in.Close()
+	defer reader.Close()
 
-	reader := csvin.New(in, csvin.Options{Comma: ',', TrimSpace: true})
 	header, _, err := reader.Header()
 	if err != nil {
 		return fmt.Errorf("read header: %w", err)
 	}
 
-	findCol := func(cands ...string) (string, bool) {
+	findCol := func(cands ...string) (string, csvin.Col) {
 		for _, h := range header {
 			hn := strings.TrimSpace(h)
 			for _, c := range cands {
 				if hn == c {
-					return hn, true
+					return hn, reader.Col(hn)
 				}
 			}
 		}
-		return "", false
+		return "", csvin.NoCol
 	}
 
-	ipCol, ok := findCol("host_ip", "ClientIP", "client_ip", "ip", "remote_addr")
-	if !ok {
+	ipName, ipCol := findCol("host_ip", "ClientIP", "client_ip", "ip", "remote_addr")
+	if ipCol == csvin.NoCol {
 		return fmt.Errorf("could not find an IP column (tried: host_ip, ClientIP, client_ip, ip, remote_addr)")
 	}
-	log.Printf("verify: using IP column %q", ipCol)
-
-	normalizeIPKey := func(ip string) string {
-		ip = strings.TrimSpace(ip)
-		if strings.HasPrefix(ip, "[") && strings.HasSuffix(ip, "]") {
-			ip = strings.Trim(ip, "[]")
-		}
-		return ip
-	}
+	log.Printf("verify: using IP column %q", ipName)
 
 	unique := make(map[string]struct{})
 	var totalRows, emptyIPs int
 
 	for {
-		row, err := reader.Next()
+		rec, err := reader.NextRecord()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			continue
+			return fmt.Errorf("read input: %w", err)
 		}
 		totalRows++
-		ip := normalizeIPKey(row[ipCol])
+		ip := normalizeIPKey(rec.At(ipCol))
 		if ip == "" || ip == "-" {
 			emptyIPs++
 			continue
@@ -354,11 +559,65 @@ func runVerify(ctx context.Context, inPath, outPath string, workers int) error {
 		ips = append(ips, ip)
 	}
 
-	log.Printf("scanned rows=%d, empty_ip=%d, unique_ips=%d", totalRows, emptyIPs, len(ips))
+	log.Printf("scanned rows=%d, bad=%d, empty_ip=%d, unique_ips=%d", totalRows, reader.Stats().Bad, emptyIPs, len(ips))
 	if len(ips) == 0 {
 		return verifier.WriteResultsCSV(outPath, nil)
 	}
 
+	results, err := verifyIPSet(ctx, res, vc, ips, workers)
+	if err != nil {
+		return err
+	}
+
+	if err := verifier.WriteResultsCSV(outPath, results); err != nil {
+		return fmt.Errorf("write verified: %w", err)
+	}
+	log.Printf("verify done: wrote %d rows to %s", len(results), outPath)
+	return nil
+}
+
+// verifyIPSet: CIDR liste → DNS keš → FCrDNS za ostatak. Zajedničko za
+// --stage verify i --stage all.
+func verifyIPSet(ctx context.Context, res verifier.Resolver, vc verifyCacheOpts, ips []string, workers int) ([]verifier.Result, error) {
+	// CIDR liste (ako postoje u bots fajlu) rešavaju IP bez mreže i bez keša.
+	cidrHits, ips := verifier.SplitByCIDR(ips)
+	if len(cidrHits) > 0 {
+		log.Printf("verify: %d IPs matched official CIDR lists", len(cidrHits))
+	}
+
+	// Keš: pogoci idu direktno u rezultat, DNS radimo samo za promašaje.
+	var (
+		cache  *dnscache.Cache
+		cached []verifier.Result
+	)
+	if vc.Path != "" {
+		var err error
+		opt := vc.TTL
+		opt.RulesHash = botdetector.Fingerprint()
+		cache, err = dnscache.Open(vc.Path, opt)
+		if err != nil {
+			return nil, err
+		}
+		defer cache.Close()
+		if n := cache.Stats.Invalidated; n > 0 {
+			log.Printf("dns cache: bot rules changed, dropped %d cached verdicts", n)
+		}
+
+		if vc.Prune {
+			n, err := cache.Prune(time.Now())
+			if err != nil {
+				return nil, fmt.Errorf("dns cache prune: %w", err)
+			}
+			log.Printf("dns cache: pruned %d expired or corrupt entries", n)
+		}
+		if !vc.Bypass {
+			cached, ips, err = cache.Lookup(ips, time.Now())
+			if err != nil {
+				return nil, fmt.Errorf("dns cache lookup: %w", err)
+			}
+		}
+	}
+
 	progress := make(chan int, 100)
 	go func() {
 		tick := time.NewTicker(5 * time.Second)
@@ -378,17 +637,22 @@ func runVerify(ctx context.Context, inPath, outPath string, workers int) error {
 		}
 	}()
 
-	results, err := verifier.VerifyIPs(ctx, ips, workers, 8*time.Second, progress)
+	results, err := verifier.VerifyIPs(ctx, res, ips, workers, 8*time.Second, progress)
 	close(progress)
 	if err != nil {
-		return fmt.Errorf("verify: %w", err)
+		return nil, fmt.Errorf("verify: %w", err)
 	}
 
-	if err := verifier.WriteResultsCSV(outPath, results); err != nil {
-		return fmt.Errorf("write verified: %w", err)
+	if cache != nil {
+		if err := cache.Store(results, time.Now()); err != nil {
+			return nil, fmt.Errorf("dns cache store: %w", err)
+		}
+		st := cache.Stats
+		log.Printf("dns cache: hits=%d misses=%d expired=%d corrupt=%d stored=%d bypass=%v", st.Hits, st.Misses, st.Expired, st.Corrupt, st.Stored, vc.Bypass)
+		results = append(cached, results...)
 	}
-	log.Printf("verify done: wrote %d rows to %s", len(results), outPath)
-	return nil
+	results = append(cidrHits, results...)
+	return results, nil
 }
 
 // ---------- Helpers for merge ----------
@@ -487,21 +751,43 @@ func baseDomain(host string) string {
 }
 
 // ---------- STAGE 4: merge ----------
-func runMerge(ctx context.Context, finalPath, verifiedPath, outPath string, uaPtrVerify bool) error {
-	type verPair struct {
-		name string
-		flag string // "1" or "0"
+type verPair struct {
+	name   string
+	flag   string // "1" or "0"
+	host   string // FCrDNS potvrđen hostname
+	reason string // verifier.Reason* kod
+	method string // "fcrdns" | "cidr"
+}
+
+func verPairFromResult(r verifier.Result) verPair {
+	flag := "0"
+	if r.Verified {
+		flag = "1"
+	}
+	return verPair{name: r.BotName, flag: flag, host: r.Hostname, reason: r.Reason, method: r.Method}
+}
+
+func runMerge(ctx context.Context, finalPath, verifiedPath, outPath string, uaPtrVerify, trustSource bool, spoofReportPath string) (err error) {
+	if finalPath == "" || verifiedPath == "" || outPath == "" {
+		return fmt.Errorf("merge: --in, --verified and --out are required")
+	}
+	if !iox.IsStdio(outPath) && (outPath == finalPath || outPath == verifiedPath) {
+		return fmt.Errorf("merge: output path must differ from inputs (got %q)", outPath)
 	}
+	if iox.IsStdio(finalPath) && iox.IsStdio(verifiedPath) {
+		return fmt.Errorf("merge: only one of --in and --verified can be stdin")
+	}
+
 	verMap := make(map[string]verPair, 1<<16)
+	var verRows, verDup int64
 
 	// Load verified.csv -> map[IP]verPair
-	vIn, err := iox.OpenAuto(verifiedPath)
+	vReader, err := csvin.Open(verifiedPath, csvin.Options{})
 	if err != nil {
 		return fmt.Errorf("open verified: %w", err)
 	}
-	defer vIn.Close()
+	defer vReader.Close()
 
-	vReader := csvin.New(vIn, csvin.Options{Comma: ',', TrimSpace: true})
 	vHeader, _, err := vReader.Header()
 	if err != nil {
 		return fmt.Errorf("read verified header: %w", err)
@@ -518,24 +804,30 @@ func runMerge(ctx context.Context, finalPath, verifiedPath, outPath string, uaPt
 		}
 	}
 	if !hasHost || !hasBN || !hasVF {
-		return fmt.Errorf("verified.csv must contain 'host_ip','botName','verified'")
+		return fmt.Errorf("merge: %s must contain 'host_ip','botName','verified' (got %v)", verifiedPath, vHeader)
 	}
 
+	vIP, vFlag, vBot := vReader.Col("host_ip"), vReader.Col("verified"), vReader.Col("botName")
+	vHost, vReason, vMethod := vReader.Col("hostname"), vReader.Col("reason"), vReader.Col("method")
 	for {
-		row, err := vReader.Next()
+		rec, err := vReader.NextRecord()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			continue
+			return fmt.Errorf("read verified: %w", err)
 		}
-		ip := normalizeIPKey(row["host_ip"])
+		ip := normalizeIPKey(rec.At(vIP))
 		if ip == "" {
 			continue
 		}
+		verRows++
+		if _, ok := verMap[ip]; ok {
+			verDup++
+		}
 
 		var vflag string
-		switch strings.TrimSpace(row["verified"]) {
+		switch strings.TrimSpace(rec.At(vFlag)) {
 		case "1", "true", "TRUE", "True", "yes", "y":
 			vflag = "1"
 		default:
@@ -543,25 +835,31 @@ func runMerge(ctx context.Context, finalPath, verifiedPath, outPath string, uaPt
 		}
 
 		verMap[ip] = verPair{
-			name: strings.TrimSpace(row["botName"]),
-			flag: vflag,
+			name:   strings.TrimSpace(rec.At(vBot)),
+			flag:   vflag,
+			host:   strings.TrimSpace(rec.At(vHost)),
+			reason: strings.TrimSpace(rec.At(vReason)),
+			method: strings.TrimSpace(rec.At(vMethod)),
 		}
 	}
 
 	// Open final.csv and prepare output
-	in, err := iox.OpenAuto(finalPath)
+	reader, err := csvin.Open(finalPath, csvin.Options{})
 	if err != nil {
 		return fmt.Errorf("open final: %w", err)
 	}
-	defer in.Close()
+	defer reader.Close()
 
 	out, err := iox.CreateAuto(outPath)
 	if err != nil {
 		return fmt.Errorf("create output: %w", err)
 	}
-	defer out.Close()
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
 
-	reader := csvin.New(in, csvin.Options{Comma: ',', TrimSpace: true})
 	header, _, err := reader.Header()
 	if err != nil {
 		return fmt.Errorf("read final header: %w", err)
@@ -576,13 +874,27 @@ func runMerge(ctx context.Context, finalPath, verifiedPath, outPath string, uaPt
 	if !foundHost {
 		return fmt.Errorf("final CSV must contain 'host_ip' column")
 	}
+	outHeader := outputHeader(header)
 
-	writer := csvout.New(out)
-	if err := writer.WriteHeader(schema.BaseHeader()); err != nil {
+	writer := csvout.NewFor(out, outPath)
+	if err := writer.WriteHeader(outHeader); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
-
-	var rowsIn, rowsOut, patched int64
+	strictDone, err := validateOutput("merge", writer, outHeader, outPath)
+	if err != nil {
+		return err
+	}
+	defer strictDone()
+
+	m := newMerger(verMap, uaPtrVerify, trustSource, outHeader)
+	proj := newProjection(reader, outHeader)
+	ipCol := reader.Col("host_ip")
+	var (
+		rowsIn, rowsOut int64
+		row             []string
+	)
+	seenIPs := make(map[string]struct{}, len(verMap))
+	missing := make(map[string]int64) // IP iz final-a kojeg nema u verified fajlu → broj redova
 	start := time.Now()
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
@@ -592,40 +904,27 @@ func runMerge(ctx context.Context, finalPath, verifiedPath, outPath string, uaPt
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			log.Printf("merge progress: in=%d out=%d patched=%d", rowsIn, rowsOut, patched)
+			log.Printf("merge progress: in=%d out=%d patched=%d spoofed=%d", rowsIn, rowsOut, m.patched, m.spoofed)
 		default:
-			row, err := reader.Next()
+			rec, err := reader.NextRecord()
+			if err == io.EOF {
+				goto DONE
+			}
 			if err != nil {
-				if err == io.EOF {
-					goto DONE
-				}
-				continue
+				return fmt.Errorf("read input: %w", err)
 			}
 			rowsIn++
 
-			ipKey := normalizeIPKey(row["host_ip"])
-			if p, ok := verMap[ipKey]; ok {
-				// Merge bot names (union, pipe-delimited)
-				row["botName"] = uniqJoinPipe(row["botName"], p.name)
-				// Verified from verified.csv
-				row["verified"] = p.flag
-
-				// Optional heuristic: UA↔PTR base-domain match => verified=1
-				if uaPtrVerify && row["verified"] != "1" {
-					ua := strings.ToLower(row["user_agent"])
-					ptrBlob := strings.ToLower(row["botName"])
-					if uaPtrSameBaseDomain(ua, ptrBlob) {
-						row["verified"] = "1"
-					}
+			if ip := normalizeIPKey(rec.At(ipCol)); ip != "" && ip != "-" {
+				seenIPs[ip] = struct{}{}
+				if _, ok := verMap[ip]; !ok {
+					missing[ip]++
 				}
-				patched++
 			}
+			row = proj.fill(rec.Fields(), row)
+			m.apply(row)
 
-			outRow := make([]string, len(schema.BaseColumns))
-			for i, c := range schema.BaseColumns {
-				outRow[i] = row[c.Name]
-			}
-			if err := writer.WriteRow(outRow); err != nil {
+			if err := writer.WriteRow(row); err != nil {
 				return fmt.Errorf("write row: %w", err)
 			}
 			rowsOut++
@@ -636,49 +935,263 @@ DONE:
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("flush: %w", err)
 	}
-	log.Printf("merge done. in=%d out=%d patched=%d time=%s", rowsIn, rowsOut, patched, time.Since(start))
+	if err := strictDone(); err != nil {
+		return err
+	}
+	log.Printf("merge done. in=%d out=%d bad=%d patched=%d spoofed=%d time=%s", rowsIn, rowsOut, reader.Stats().Bad, m.patched, m.spoofed, time.Since(start))
+
+	if err := checkVerifiedCoverage(verifiedPath, verMap, verRows, verDup, seenIPs, missing); err != nil {
+		return err
+	}
+	return m.report(spoofReportPath)
+}
+
+// checkVerifiedCoverage poredi verified fajl sa unikatnim IP-ovima iz
+// enriched ulaza: loguje IP-ove koji nikad nisu verifikovani i višak u
+// verified fajlu. Ako se skupovi uopšte ne preklapaju, verified fajl je
+// očigledno od drugog ulaza i merge pada.
+func checkVerifiedCoverage(verifiedPath string, verMap map[string]verPair, verRows, verDup int64, seenIPs map[string]struct{}, missing map[string]int64) error {
+	extra := 0
+	for ip := range verMap {
+		if _, ok := seenIPs[ip]; !ok {
+			extra++
+		}
+	}
+	log.Printf("merge check: verified_rows=%d verified_unique=%d duplicates=%d input_unique_ips=%d never_verified=%d not_in_input=%d",
+		verRows, len(verMap), verDup, len(seenIPs), len(missing), extra)
+
+	if len(missing) > 0 {
+		ips := make([]string, 0, len(missing))
+		for ip := range missing {
+			ips = append(ips, ip)
+		}
+		sort.Slice(ips, func(i, j int) bool {
+			if missing[ips[i]] == missing[ips[j]] {
+				return ips[i] < ips[j]
+			}
+			return missing[ips[i]] > missing[ips[j]]
+		})
+		const maxShown = 20
+		for i, ip := range ips {
+			if i == maxShown {
+				log.Printf("merge check: … and %d more never-verified IPs", len(ips)-maxShown)
+				break
+			}
+			log.Printf("merge check: never verified ip=%s rows=%d", ip, missing[ip])
+		}
+	}
+
+	if len(seenIPs) > 0 && len(missing) == len(seenIPs) {
+		return fmt.Errorf("merge: none of %d input IPs found in %s — verified file does not belong to this input", len(seenIPs), verifiedPath)
+	}
 	return nil
 }
 
+// merger: per-row logika merge faze (verified.csv → red), deljena između
+// --stage merge i --stage all. Radi nad redom u izlaznom header-u (bind).
+type merger struct {
+	verMap      map[string]verPair
+	uaPtrVerify bool
+	trustSource bool // --trust-source-verified
+
+	col struct { // indeksi kolona u header-u; -1 = kolona ne postoji
+		ip, bot, verified, vHost, vReason, vMethod, ua, spoofed int
+	}
+
+	patched, spoofed int64
+	spoofs           map[string]*spoofStat
+}
+
+type spoofStat struct {
+	hits int64
+	ips  map[string]struct{}
+}
+
+func newMerger(verMap map[string]verPair, uaPtrVerify, trustSource bool, header []string) *merger {
+	m := &merger{verMap: verMap, uaPtrVerify: uaPtrVerify, trustSource: trustSource, spoofs: make(map[string]*spoofStat)}
+	m.col.ip = colIndex(header, "host_ip")
+	m.col.bot = colIndex(header, "botName")
+	m.col.verified = colIndex(header, "verified")
+	m.col.vHost = colIndex(header, "verified_host")
+	m.col.vReason = colIndex(header, "verify_reason")
+	m.col.vMethod = colIndex(header, "verify_method")
+	m.col.ua = colIndex(header, "user_agent")
+	m.col.spoofed = colIndex(header, "spoofed")
+	return m
+}
+
+func (m *merger) apply(row []string) {
+	ipKey := normalizeIPKey(cell(row, m.col.ip))
+	// --trust-source-verified: verified=1 bez verify_reason je presuda izvora
+	// (npr. Cloudflare VerifiedBotCategory) i verified.csv je ne menja.
+	source := m.trustSource && cell(row, m.col.verified) == "1" && cell(row, m.col.vReason) == ""
+	verifiedAs := ""
+	p, hasVerdict := m.verMap[ipKey]
+	if hasVerdict {
+		if p.flag == "1" {
+			verifiedAs = p.name
+		}
+		if !source {
+			// Merge bot names (union, pipe-delimited)
+			setCell(row, m.col.bot, uniqJoinPipe(cell(row, m.col.bot), p.name))
+			// Verified from verified.csv (+ objašnjenje presude)
+			setCell(row, m.col.verified, p.flag)
+			setCell(row, m.col.vHost, p.host)
+			setCell(row, m.col.vReason, p.reason)
+			setCell(row, m.col.vMethod, p.method)
+		}
+
+		// Optional heuristic: UA↔PTR base-domain match => verified=1
+		if m.uaPtrVerify && cell(row, m.col.verified) != "1" {
+			ua := strings.ToLower(cell(row, m.col.ua))
+			ptrBlob := strings.ToLower(cell(row, m.col.bot))
+			if uaPtrSameBaseDomain(ua, ptrBlob) {
+				setCell(row, m.col.verified, "1")
+				setCell(row, m.col.vReason, "ua_ptr_heuristic")
+			}
+		}
+		m.patched++
+	}
+
+	// spoofed: UA tvrdi proverljivog bota, a IP nije potvrđen kao taj bot.
+	// Bez presude (IP nije u verified.csv) ili sa neodlučnom presudom
+	// (lookup_error) se ne zna, pa spoofed ostaje prazan.
+	setCell(row, m.col.spoofed, "0")
+	claimed, ok := botdetector.MatchUA(cell(row, m.col.ua))
+	if !ok || !claimed.Verifiable {
+		return
+	}
+	genuine := cell(row, m.col.verified) == "1" &&
+		(source || strings.EqualFold(verifiedAs, claimed.Name) || cell(row, m.col.vReason) == "ua_ptr_heuristic")
+	switch {
+	case genuine:
+	case !hasVerdict || p.reason == verifier.ReasonLookupError:
+		setCell(row, m.col.spoofed, "")
+	default:
+		setCell(row, m.col.spoofed, "1")
+		m.spoofed++
+		st := m.spoofs[claimed.Name]
+		if st == nil {
+			st = &spoofStat{ips: make(map[string]struct{})}
+			m.spoofs[claimed.Name] = st
+		}
+		st.hits++
+		st.ips[ipKey] = struct{}{}
+	}
+}
+
+// report loguje spoofed brojače po botu i (opciono) piše --spoof-report.
+func (m *merger) report(spoofReportPath string) error {
+	bots := make([]string, 0, len(m.spoofs))
+	for name := range m.spoofs {
+		bots = append(bots, name)
+	}
+	sort.Slice(bots, func(i, j int) bool {
+		if m.spoofs[bots[i]].hits == m.spoofs[bots[j]].hits {
+			return bots[i] < bots[j]
+		}
+		return m.spoofs[bots[i]].hits > m.spoofs[bots[j]].hits
+	})
+	for _, name := range bots {
+		log.Printf("merge spoofed: bot=%s hits=%d unique_ips=%d", name, m.spoofs[name].hits, len(m.spoofs[name].ips))
+	}
+	if spoofReportPath != "" {
+		if err := writeSpoofReport(spoofReportPath, bots, m.spoofs); err != nil {
+			return fmt.Errorf("spoof report: %w", err)
+		}
+		log.Printf("merge: spoof report written to %s", spoofReportPath)
+	}
+	return nil
+}
+
+// writeSpoofReport: jedan red po botu (botName, spoofed_hits, unique_ips, ips),
+// IP adrese su spojene sa '|' i sortirane, spremne za blok listu.
+func writeSpoofReport(path string, bots []string, spoofs map[string]*spoofStat) (err error) {
+	out, err := iox.CreateAuto(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
+
+	w := csvout.New(out)
+	if err := w.WriteHeader([]string{"botName", "spoofed_hits", "unique_ips", "ips"}); err != nil {
+		return err
+	}
+	for _, name := range bots {
+		st := spoofs[name]
+		ips := make([]string, 0, len(st.ips))
+		for ip := range st.ips {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		if err := w.WriteRow([]string{
+			name,
+			strconv.FormatInt(st.hits, 10),
+			strconv.Itoa(len(ips)),
+			strings.Join(ips, "|"),
+		}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
 // ---------- STAGE 5: aibots ----------
 // Ulaz: merged.csv; Izlaz: merged_ai.csv
 // Dodaje novu kolonu "AiBots". Ako UA sadrži neku AI-liniju, upisuje PRVU prepoznatu; inače '-'.
-func runAIBots(ctx context.Context, inPath, outPath string) error {
+func runAIBots(ctx context.Context, inPath, outPath string) (err error) {
 	if inPath == "" || outPath == "" {
 		return fmt.Errorf("aibots: --in and --out are required")
 	}
-	if inPath == outPath {
+	if inPath == outPath && !iox.IsStdio(inPath) {
 		return fmt.Errorf("aibots: input and output paths must differ (got %q)", inPath)
 	}
 
-	in, err := iox.OpenAuto(inPath)
+	reader, err := csvin.Open(inPath, csvin.Options{})
 	if err != nil {
 		return fmt.Errorf("open input: %w", err)
 	}
-	defer in.Close()
+	defer reader.Close()
 
 	out, err := iox.CreateAuto(outPath)
 	if err != nil {
 		return fmt.Errorf("create output: %w", err)
 	}
-	defer out.Close()
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
 
-	reader := csvin.New(in, csvin.Options{Comma: ',', TrimSpace: true})
-	_, _, err = reader.Header()
+	inHeader, _, err := reader.Header()
 	if err != nil {
 		return fmt.Errorf("read header: %w", err)
 	}
 
-	// Header = BaseHeader + AiBots
-	base := schema.BaseHeader()
+	// Header = BaseHeader + dodatne kolone + AiBots
+	base := outputHeader(withoutColumn(inHeader, "AiBots"))
 	outHeader := append(append([]string(nil), base...), "AiBots")
 
-	writer := csvout.New(out)
+	writer := csvout.NewFor(out, outPath)
 	if err := writer.WriteHeader(outHeader); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
-
-	var rowsIn, rowsOut, tagged int64
+	strictDone, err := validateOutput("aibots", writer, outHeader, outPath)
+	if err != nil {
+		return err
+	}
+	defer strictDone()
+
+	proj := newProjection(reader, base)
+	uaCol := reader.Col("user_agent")
+	var (
+		rowsIn, rowsOut, tagged int64
+		outRow                  []string
+	)
 	start := time.Now()
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
@@ -690,27 +1203,23 @@ func runAIBots(ctx context.Context, inPath, outPath string) error {
 		case <-ticker.C:
 			log.Printf("aibots progress: in=%d out=%d tagged=%d", rowsIn, rowsOut, tagged)
 		default:
-			row, err := reader.Next()
+			rec, err := reader.NextRecord()
+			if err == io.EOF {
+				goto DONE
+			}
 			if err != nil {
-				if err == io.EOF {
-					goto DONE
-				}
-				continue
+				return fmt.Errorf("read input: %w", err)
 			}
 			rowsIn++
 
 			ai := "-"
-			if found := aibots.Detect(row["user_agent"]); len(found) > 0 {
+			if found := aibots.Detect(rec.At(uaCol)); len(found) > 0 {
 				ai = found[0] // prva detekcija radi preglednosti
 				tagged++
 			}
 
-			// out row = BaseColumns order + AiBots kao poslednja kolona
-			outRow := make([]string, 0, len(base)+1)
-			for _, c := range schema.BaseColumns {
-				outRow = append(outRow, row[c.Name])
-			}
-			outRow = append(outRow, ai)
+			// out row = BaseColumns order (+ dodatne) + AiBots kao poslednja kolona
+			outRow = append(proj.fill(rec.Fields(), outRow), ai)
 
 			if err := writer.WriteRow(outRow); err != nil {
 				return fmt.Errorf("write row: %w", err)
@@ -723,6 +1232,9 @@ DONE:
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("flush: %w", err)
 	}
-	log.Printf("aibots done. in=%d out=%d tagged=%d time=%s", rowsIn, rowsOut, tagged, time.Since(start))
+	if err := strictDone(); err != nil {
+		return err
+	}
+	log.Printf("aibots done. in=%d out=%d bad=%d tagged=%d time=%s", rowsIn, rowsOut, reader.Stats().Bad, tagged, time.Since(start))
 	return nil
 }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+
+	"parser/internal/schema"
+	"parser/internal/verifier"
+)
+
+const (
+	uaGooglebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	uaBrowser   = "Mozilla/5.0 (Windows NT 10.0) Chrome/120"
+)
+
+// mergeRow: BaseHeader red sa zadatim kolonama.
+func mergeRow(vals map[string]string) []string {
+	h := schema.BaseHeader()
+	row := make([]string, len(h))
+	for i, c := range h {
+		row[i] = vals[c]
+	}
+	return row
+}
+
+func TestMergerApply(t *testing.T) {
+	verMap := map[string]verPair{
+		"66.249.66.1": {name: "Googlebot", flag: "1", host: "crawl-66-249-66-1.googlebot.com", reason: verifier.ReasonConfirmed, method: "fcrdns"},
+		"66.249.66.2": {name: "Googlebot", flag: "1", reason: verifier.ReasonCIDRMatch, method: "cidr"},
+		"1.2.3.4":     {name: "evil.net", flag: "0", reason: verifier.ReasonUnknownPTR, method: "fcrdns"},
+		"5.6.7.8":     {name: "unable to verify bot", flag: "0", reason: verifier.ReasonNoPTR, method: "fcrdns"},
+		"9.9.9.9":     {flag: "0", reason: verifier.ReasonLookupError, method: "fcrdns"},
+	}
+	cases := []struct {
+		name string
+		in   map[string]string
+		want map[string]string // proverene kolone
+	}{
+		{
+			name: "potvrđen Googlebot",
+			in:   map[string]string{"host_ip": "66.249.66.1", "user_agent": uaGooglebot, "botName": "Googlebot"},
+			want: map[string]string{"verified": "1", "spoofed": "0", "botName": "Googlebot",
+				"verified_host": "crawl-66-249-66-1.googlebot.com", "verify_reason": "fcrdns_ok", "verify_method": "fcrdns"},
+		},
+		{
+			name: "potvrđen preko CIDR",
+			in:   map[string]string{"host_ip": "66.249.66.2", "user_agent": uaGooglebot},
+			want: map[string]string{"verified": "1", "spoofed": "0", "verify_method": "cidr"},
+		},
+		{
+			name: "UA Googlebot sa tuđeg PTR-a",
+			in:   map[string]string{"host_ip": "1.2.3.4", "user_agent": uaGooglebot, "botName": "Googlebot"},
+			want: map[string]string{"verified": "0", "spoofed": "1", "botName": "Googlebot|evil.net", "verify_reason": "ptr_unknown"},
+		},
+		{
+			name: "UA Googlebot bez PTR-a",
+			in:   map[string]string{"host_ip": "5.6.7.8", "user_agent": uaGooglebot},
+			want: map[string]string{"verified": "0", "spoofed": "1", "verify_reason": "no_ptr"},
+		},
+		{
+			name: "lookup_error nije presuda",
+			in:   map[string]string{"host_ip": "9.9.9.9", "user_agent": uaGooglebot},
+			want: map[string]string{"verified": "0", "spoofed": "", "verify_reason": "lookup_error"},
+		},
+		{
+			name: "IP bez presude",
+			in:   map[string]string{"host_ip": "8.8.8.8", "user_agent": uaGooglebot},
+			want: map[string]string{"verified": "", "spoofed": ""},
+		},
+		{
+			name: "verified=1 iz izvora, verified.csv kaže 0",
+			in:   map[string]string{"host_ip": "1.2.3.4", "user_agent": uaGooglebot, "verified": "1"},
+			want: map[string]string{"verified": "0", "spoofed": "1", "verify_reason": "ptr_unknown"},
+		},
+		{
+			name: "verified=1 iz izvora, IP bez presude",
+			in:   map[string]string{"host_ip": "8.8.8.8", "user_agent": uaGooglebot, "verified": "1"},
+			want: map[string]string{"verified": "1", "spoofed": ""},
+		},
+		{
+			name: "obični browser",
+			in:   map[string]string{"host_ip": "1.2.3.4", "user_agent": uaBrowser},
+			want: map[string]string{"verified": "0", "spoofed": "0"},
+		},
+	}
+
+	m := newMerger(verMap, false, false, schema.BaseHeader())
+	h := schema.BaseHeader()
+	for _, tc := range cases {
+		row := mergeRow(tc.in)
+		m.apply(row)
+		for i, c := range h {
+			if w, ok := tc.want[c]; ok && row[i] != w {
+				t.Errorf("%s: %s = %q, want %q", tc.name, c, row[i], w)
+			}
+		}
+	}
+	if m.spoofed != 3 {
+		t.Errorf("spoofed = %d, want 3", m.spoofed)
+	}
+	if st := m.spoofs["Googlebot"]; st == nil || st.hits != 3 || len(st.ips) != 2 {
+		t.Errorf("spoofs[Googlebot] = %+v, want hits=3 ips=2", st)
+	}
+}
+
+// --trust-source-verified: verified=1 bez verify_reason (presuda izvora)
+// ostaje; ostali redovi se spajaju kao bez flaga.
+func TestMergerTrustSource(t *testing.T) {
+	verMap := map[string]verPair{
+		"1.2.3.4": {name: "evil.net", flag: "0", reason: verifier.ReasonUnknownPTR, method: "fcrdns"},
+		"9.9.9.9": {flag: "0", reason: verifier.ReasonLookupError, method: "fcrdns"},
+	}
+	cases := []struct {
+		name string
+		in   map[string]string
+		want map[string]string
+	}{
+		{
+			name: "izvor verified=1, verified.csv kaže 0",
+			in:   map[string]string{"host_ip": "1.2.3.4", "user_agent": uaGooglebot, "botName": "Googlebot", "verified": "1"},
+			want: map[string]string{"verified": "1", "spoofed": "0", "botName": "Googlebot", "verify_reason": ""},
+		},
+		{
+			name: "izvor verified=1, lookup_error",
+			in:   map[string]string{"host_ip": "9.9.9.9", "user_agent": uaGooglebot, "verified": "1"},
+			want: map[string]string{"verified": "1", "spoofed": "0", "verify_reason": ""},
+		},
+		{
+			name: "izvor verified=1, IP bez presude",
+			in:   map[string]string{"host_ip": "8.8.8.8", "user_agent": uaGooglebot, "verified": "1"},
+			want: map[string]string{"verified": "1", "spoofed": "0"},
+		},
+		{
+			name: "verified=1 sa verify_reason (ranije spojen fajl) ide iz verified.csv",
+			in:   map[string]string{"host_ip": "1.2.3.4", "user_agent": uaGooglebot, "verified": "1", "verify_reason": "fcrdns_ok"},
+			want: map[string]string{"verified": "0", "spoofed": "1", "verify_reason": "ptr_unknown"},
+		},
+		{
+			name: "bez presude izvora: spoofed kao bez flaga",
+			in:   map[string]string{"host_ip": "1.2.3.4", "user_agent": uaGooglebot},
+			want: map[string]string{"verified": "0", "spoofed": "1"},
+		},
+		{
+			name: "bez presude izvora: lookup_error",
+			in:   map[string]string{"host_ip": "9.9.9.9", "user_agent": uaGooglebot},
+			want: map[string]string{"verified": "0", "spoofed": ""},
+		},
+	}
+
+	m := newMerger(verMap, false, true, schema.BaseHeader())
+	h := schema.BaseHeader()
+	for _, tc := range cases {
+		row := mergeRow(tc.in)
+		m.apply(row)
+		for i, c := range h {
+			if w, ok := tc.want[c]; ok && row[i] != w {
+				t.Errorf("%s: %s = %q, want %q", tc.name, c, row[i], w)
+			}
+		}
+	}
+	if m.spoofed != 2 {
+		t.Errorf("spoofed = %d, want 2", m.spoofed)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ---------- paralelna obrada redova (normalize, enrich) ----------
+//
+// Čitač puni batch-eve ulaznih redova, --workers radnika ih obrađuje, a
+// pisac (pozivalac) ih vraća u redosledu čitanja (seq), pa je izlaz isti
+// kao sa jednim radnikom. Batch-evi i izlazni redovi se recikliraju kroz
+// free kanal: u ustaljenom stanju nema alokacija po redu osim samog
+// csv.Reader zapisa.
+
+const rowBatchSize = 512
+
+type rowBatch struct {
+	seq int64
+	n   int
+	in  [][]string
+	out [][]string
+}
+
+// rowFunc obrađuje jedan red: rezultat se upisuje u out (append na out[:0])
+// i vraća. Svaki radnik dobija svoju rowFunc (newRowFunc), pa scratch
+// baferi ne moraju biti deljeni.
+type rowFunc func(rec, out []string) []string
+
+// rowStats: Bad popunjava pozivalac iz csvin.Reader.Stats (loše redove
+// preskače čitač, read ih ne vraća); svaka greška iz read prekida fazu.
+type rowStats struct {
+	In, Out, Bad int64
+}
+
+// processRows: read → workers × fn → write (redosledom ulaza).
+func processRows(ctx context.Context, stage string, workers int, read func() ([]string, error), newRowFunc func() rowFunc, write func([]string) error) (rowStats, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	nb := 4 * workers
+	free := make(chan *rowBatch, nb)
+	for i := 0; i < nb; i++ {
+		free <- &rowBatch{in: make([][]string, rowBatchSize), out: make([][]string, rowBatchSize)}
+	}
+	todo := make(chan *rowBatch, workers)
+	done := make(chan *rowBatch, nb) // kapacitet = svi batch-evi: radnici nikad ne blokiraju
+	stop := make(chan struct{})      // pisac je pao: čitač staje
+
+	var (
+		readIn  atomic.Int64
+		readErr = make(chan error, 1)
+	)
+	go func() {
+		defer close(todo)
+		var seq int64
+		for {
+			var b *rowBatch
+			select {
+			case b = <-free:
+			case <-stop:
+				return
+			}
+			b.seq, b.n = seq, 0
+			seq++
+			var err error
+			for b.n < rowBatchSize {
+				var rec []string
+				if rec, err = read(); err != nil {
+					break
+				}
+				b.in[b.n] = rec
+				b.n++
+			}
+			if err == nil {
+				err = ctx.Err()
+			}
+			readIn.Add(int64(b.n))
+			if err != nil && err != io.EOF {
+				readErr <- err
+				return
+			}
+			select {
+			case todo <- b:
+			case <-stop:
+				return
+			}
+			if err == io.EOF {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		fn := newRowFunc()
+		go func() {
+			defer wg.Done()
+			for b := range todo {
+				for j := 0; j < b.n; j++ {
+					b.out[j] = fn(b.in[j], b.out[j])
+				}
+				done <- b
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var (
+		st      rowStats
+		next    int64
+		werr    error
+		pending = make(map[int64]*rowBatch, nb)
+	)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			log.Printf("%s progress: in=%d out=%d", stage, readIn.Load(), st.Out)
+			continue
+		case b, ok := <-done:
+			if !ok {
+				goto DONE
+			}
+			pending[b.seq] = b
+		}
+		for {
+			b, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			for j := 0; j < b.n && werr == nil; j++ {
+				if werr = write(b.out[j]); werr != nil {
+					close(stop)
+					break
+				}
+				st.Out++
+			}
+			clear(b.in[:b.n]) // ne drži ulazne zapise do sledećeg punjenja
+			free <- b
+		}
+	}
+
+DONE:
+	st.In = readIn.Load()
+	if werr != nil {
+		return st, werr
+	}
+	select {
+	case err := <-readErr:
+		return st, err
+	default:
+	}
+	return st, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counter: read koji vraća redove "0", "1", … do n (n < 0 → bez kraja).
+func counter(n int64, reads *atomic.Int64) func() ([]string, error) {
+	return func() ([]string, error) {
+		i := reads.Load()
+		if n >= 0 && i >= n {
+			return nil, io.EOF
+		}
+		reads.Add(1)
+		return []string{strconv.FormatInt(i, 10)}, nil
+	}
+}
+
+// tagRow: izlaz je ulaz + "x"; svaki treći batch radi sporije, da bi
+// radnici završavali van redosleda.
+func tagRow() rowFunc {
+	return func(rec, out []string) []string {
+		if i, _ := strconv.Atoi(rec[0]); (i/rowBatchSize)%3 == 0 {
+			for k := 0; k < 20; k++ {
+				runtime.Gosched()
+			}
+		}
+		return append(out[:0], rec[0], "x")
+	}
+}
+
+// noLeak: posle processRows ne sme ostati nijedna njegova gorutina.
+func noLeak(t *testing.T, base int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > base {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("gorutine: %d, want <= %d\n%s", runtime.NumGoroutine(), base, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestProcessRowsOrder(t *testing.T) {
+	const n = 3*rowBatchSize + 37 // poslednji batch nije pun
+	for _, workers := range []int{1, 2, 8} {
+		base := runtime.NumGoroutine()
+		var reads atomic.Int64
+		var got []string
+		st, err := processRows(context.Background(), "test", workers, counter(n, &reads), tagRow, func(row []string) error {
+			if len(row) != 2 || row[1] != "x" {
+				t.Fatalf("workers=%d: red %v", workers, row)
+			}
+			got = append(got, row[0])
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("workers=%d: %v", workers, err)
+		}
+		if st.In != n || st.Out != n || len(got) != n {
+			t.Fatalf("workers=%d: in=%d out=%d written=%d, want %d", workers, st.In, st.Out, len(got), n)
+		}
+		for i, v := range got {
+			if v != strconv.Itoa(i) {
+				t.Fatalf("workers=%d: red %d = %s, want %d (redosled ulaza)", workers, i, v, i)
+			}
+		}
+		noLeak(t, base)
+	}
+}
+
+// Greška pisca, greška čitača i otkazan ctx prekidaju fazu: čitač staje
+// (ne čita ulaz bez kraja) i sve gorutine izlaze.
+func TestProcessRowsStop(t *testing.T) {
+	errWrite := errors.New("disk full")
+	errRead := errors.New("bad gzip")
+	cases := []struct {
+		name  string
+		read  func(reads *atomic.Int64, cancel func()) func() ([]string, error)
+		write func(i int64) error
+		want  error
+	}{
+		{
+			name: "greška pisca",
+			read: func(reads *atomic.Int64, _ func()) func() ([]string, error) { return counter(-1, reads) },
+			write: func(i int64) error {
+				if i == 700 {
+					return errWrite
+				}
+				return nil
+			},
+			want: errWrite,
+		},
+		{
+			name: "greška čitača",
+			read: func(reads *atomic.Int64, _ func()) func() ([]string, error) {
+				next := counter(-1, reads)
+				return func() ([]string, error) {
+					if reads.Load() == 600 {
+						return nil, errRead
+					}
+					return next()
+				}
+			},
+			write: func(int64) error { return nil },
+			want:  errRead,
+		},
+		{
+			name: "otkazan ctx",
+			read: func(reads *atomic.Int64, cancel func()) func() ([]string, error) {
+				next := counter(-1, reads)
+				return func() ([]string, error) {
+					if reads.Load() == 1000 {
+						cancel()
+					}
+					return next()
+				}
+			},
+			write: func(int64) error { return nil },
+			want:  context.Canceled,
+		},
+	}
+	for _, tc := range cases {
+		for _, workers := range []int{1, 8} {
+			base := runtime.NumGoroutine()
+			ctx, cancel := context.WithCancel(context.Background())
+			var reads, written atomic.Int64
+			_, err := processRows(ctx, "test", workers, tc.read(&reads, cancel), tagRow, func([]string) error {
+				if err := tc.write(written.Load()); err != nil {
+					return err
+				}
+				written.Add(1)
+				return nil
+			})
+			cancel()
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%s, workers=%d: err = %v, want %v", tc.name, workers, err, tc.want)
+			}
+			// čitač može biti najviše pun krug batch-eva ispred pisca
+			if max := int64(4*workers+2) * rowBatchSize; reads.Load() > max {
+				t.Errorf("%s, workers=%d: pročitano %d redova posle prekida (max %d)", tc.name, workers, reads.Load(), max)
+			}
+			noLeak(t, base)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"parser/internal/aibots"
+	"parser/internal/csvin"
+	"parser/internal/csvout"
+	"parser/internal/iox"
+	"parser/internal/jsonl"
+	"parser/internal/mapper"
+	"parser/internal/schema"
+	"parser/internal/source"
+	"parser/internal/verifier"
+)
+
+// ---------- STAGE all: jsonl → normalize → enrich → verify → merge → aibots ----------
+//
+// Ceo pipeline u jednom procesu. Redovi idu kroz faze u memoriji; jedina
+// barijera je verify (treba mu kompletan skup IP adresa), pa se enriched
+// redovi jednom spuste u zstd spool (samo izlazne kolone) umesto pet
+// međufajlova (raw/normalized/final/verified/merged). Redosled izlaza je
+// redosled ulaznih linija (processRows), isti kao make all sa
+// --jsonl-ordered.
+//
+// Spool je namerno odstupanje od "materijalizuj samo IP set": merge treba
+// presudu za svaki red pre upisa, a alternativa je drugi prolaz kroz ulaz.
+// To ne radi za stdin, a za gzip/zstd JSONL znači još jednu dekompresiju i
+// JSON parsiranje svake linije, što je skuplje od jednog upisa i čitanja
+// kompresovanog spool-a (CSV samo izlaznih kolona, bez JSON ključeva).
+
+type pipelineConfig struct {
+	InPath  string
+	OutPath string
+	TempDir string
+	Workers int // jsonl+normalize+enrich radnici
+	// RejectsPath / MaxBadRatio: kao u jsonl fazi (--jsonl-rejects, --max-bad-ratio)
+	RejectsPath string
+	MaxBadRatio float64
+	// Format: --source-format; "auto" bira profil po ključevima prve
+	// ispravne JSONL linije (kao normalize po header-u)
+	Format string
+	Mapper *mapper.Mapper // normalize spec (nil → ugrađeni)
+
+	Resolver    verifier.Resolver
+	Cache       verifyCacheOpts
+	DNSWorkers  int
+	UAPtrVerify bool
+	TrustSource bool
+	SpoofReport string
+}
+
+func runPipeline(ctx context.Context, cfg pipelineConfig) error {
+	if cfg.InPath == "" || cfg.OutPath == "" {
+		return fmt.Errorf("all: --in and --out are required")
+	}
+	if cfg.InPath == cfg.OutPath && !iox.IsStdio(cfg.InPath) {
+		return fmt.Errorf("all: input and output paths must differ (got %q)", cfg.InPath)
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 8
+	}
+	if cfg.Mapper == nil {
+		cfg.Mapper = mapper.Default()
+	}
+	tmpDir := cfg.TempDir
+	if tmpDir == "" {
+		tmpDir = os.TempDir()
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "pipeline_spool_*.csv.zst")
+	if err != nil {
+		return fmt.Errorf("create spool: %w", err)
+	}
+	spoolPath := tmp.Name()
+	_ = tmp.Close()
+	defer os.Remove(spoolPath)
+
+	// Faza 1: JSONL → normalize → enrich, redovi u spool, IP-ovi u set
+	start := time.Now()
+	unique, err := pipelineIngest(ctx, cfg, spoolPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("all: phase 1 (jsonl→normalize→enrich) done in %s, unique_ips=%d", time.Since(start), len(unique))
+
+	// Faza 2: verify samo nad skupom unikatnih IP adresa
+	ips := make([]string, 0, len(unique))
+	for ip := range unique {
+		ips = append(ips, ip)
+	}
+	verMap := make(map[string]verPair, len(ips))
+	if len(ips) > 0 {
+		results, err := verifyIPSet(ctx, cfg.Resolver, cfg.Cache, ips, cfg.DNSWorkers)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			verMap[normalizeIPKey(r.IP)] = verPairFromResult(r)
+		}
+	}
+	log.Printf("all: phase 2 (verify) done, verified_ips=%d", len(verMap))
+
+	// Faza 3: spool → merge → aibots → out
+	spool, err := iox.OpenAuto(spoolPath)
+	if err != nil {
+		return fmt.Errorf("open spool: %w", err)
+	}
+	defer spool.Close()
+	return pipelineFinish(ctx, cfg, spool, verMap)
+}
+
+// pipelineIngest čita JSONL, na cfg.Workers radnika (processRows, redosled
+// ulaza) radi flatten+MapToCSV+enrich i piše base redove u zstd spool.
+// Loše linije idu kroz jsonl.LineCheck (--jsonl-rejects, --max-bad-ratio)
+// kao u jsonl fazi. Vraća skup unikatnih host_ip vrednosti.
+func pipelineIngest(ctx context.Context, cfg pipelineConfig, spoolPath string) (unique map[string]struct{}, err error) {
+	in, err := iox.OpenAuto(cfg.InPath)
+	if err != nil {
+		return nil, fmt.Errorf("open input: %w", err)
+	}
+	defer in.Close()
+
+	chk, err := jsonl.NewLineCheck(cfg.RejectsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer chk.Close()
+
+	header := cfg.Mapper.Header()
+	ipIdx := colIndex(header, "host_ip")
+
+	br := bufio.NewReaderSize(in, 1<<20)
+	prof, head, eof, err := pipelineSource(cfg.Format, br)
+	if err != nil {
+		return nil, err
+	}
+
+	// read: linija kao []string{seq, linija}; seq (0-based) je broj linije
+	// za rejects. Greška čitanja se čuva za chk.Finish, a processRows vidi
+	// kraj ulaza.
+	var (
+		seq  int64
+		rerr error
+	)
+	read := func() ([]string, error) {
+		var b []byte
+		switch {
+		case seq < int64(len(head)):
+			b = head[seq]
+		case eof:
+			return nil, io.EOF
+		default:
+			for len(b) == 0 {
+				var err error
+				b, err = br.ReadBytes('\n')
+				if err != nil {
+					eof = true
+					if err != io.EOF {
+						rerr = fmt.Errorf("read input: %w", err)
+					}
+					if len(b) == 0 {
+						return nil, io.EOF
+					}
+				}
+			}
+		}
+		rec := []string{strconv.FormatInt(seq, 10), string(b)}
+		seq++
+		return rec, nil
+	}
+
+	enr := newEnricher(header)
+	newRowFunc := func() rowFunc {
+		return func(rec, out []string) []string {
+			n, _ := strconv.ParseInt(rec[0], 10, 64)
+			flat := chk.Flatten(n, []byte(rec[1]))
+			if flat == nil {
+				return out[:0] // prazna/loša linija: pisac je preskače
+			}
+			row := append(out[:0], cfg.Mapper.Map(prof.Canonical(flat))...)
+			enr.apply(row)
+			return row
+		}
+	}
+
+	out, err := iox.CreateAuto(spoolPath)
+	if err != nil {
+		return nil, fmt.Errorf("create spool: %w", err)
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close spool: %w", cerr)
+		}
+	}()
+	w := csvout.New(out)
+	if err := w.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("write spool header: %w", err)
+	}
+
+	unique = make(map[string]struct{}, 1<<16)
+	var rowsOut int64
+	write := func(row []string) error {
+		if len(row) == 0 {
+			return nil
+		}
+		if err := w.WriteRow(row); err != nil {
+			return fmt.Errorf("write spool: %w", err)
+		}
+		rowsOut++
+		if ip := normalizeIPKey(cell(row, ipIdx)); ip != "" && ip != "-" {
+			unique[ip] = struct{}{}
+		}
+		return nil
+	}
+	if _, err := processRows(ctx, "all (ingest)", cfg.Workers, read, newRowFunc, write); err != nil {
+		return nil, err
+	}
+
+	st, err := chk.Finish(cfg.MaxBadRatio, rerr)
+	log.Printf("all jsonl lines: good=%d bad=%d empty=%d bad_ratio=%.4f", st.Good, st.Bad, st.Empty, st.BadRatio())
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("flush spool: %w", err)
+	}
+	log.Printf("all ingest done. rows=%d bad=%d", rowsOut, st.Bad)
+	return unique, nil
+}
+
+// pipelineSourcePeek: koliko linija se najviše čita tražeći prvu ispravnu
+// JSONL liniju za detekciju profila.
+const pipelineSourcePeek = 1000
+
+// pipelineSource razrešava --source-format za JSONL ulaz. JSONL nema header,
+// pa se za "auto" profil bira po ključevima prve ispravne linije (flatten,
+// isti ključevi koje vidi normalize). Pročitane linije se vraćaju u head da
+// ih ingest obradi; eof = ulaz je završen unutar head-a.
+func pipelineSource(format string, br *bufio.Reader) (prof *source.Profile, head [][]byte, eof bool, err error) {
+	var keys []string
+	for len(head) < pipelineSourcePeek && keys == nil {
+		b, rerr := br.ReadBytes('\n')
+		if len(b) > 0 {
+			head = append(head, b)
+			if flat, ferr := jsonl.FlattenLine(b); ferr == nil && flat != nil {
+				keys = make([]string, 0, len(flat))
+				for k := range flat {
+					keys = append(keys, k)
+				}
+			}
+		}
+		if rerr == io.EOF {
+			eof = true
+			break
+		}
+		if rerr != nil {
+			return nil, nil, false, fmt.Errorf("read input: %w", rerr)
+		}
+	}
+
+	prof, detected, err := source.Resolve(format, keys)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("all: %w", err)
+	}
+	switch {
+	case detected:
+		log.Printf("all: source format %s (detected from first JSONL line)", prof.Name)
+	case strings.EqualFold(strings.TrimSpace(format), "auto") || format == "":
+		log.Printf("all: WARNING: could not detect source format from first JSONL line, assuming %s", prof.Name)
+	default:
+		log.Printf("all: source format %s", prof.Name)
+	}
+	return prof, head, eof, nil
+}
+
+// pipelineFinish: merge + aibots nad spool-om, upis finalnog artefakta.
+func pipelineFinish(ctx context.Context, cfg pipelineConfig, spool io.Reader, verMap map[string]verPair) (err error) {
+	out, err := iox.CreateAuto(cfg.OutPath)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
+
+	reader := csvin.New(spool, csvin.Options{KeepSpace: true})
+	header, _, err := reader.Header()
+	if err != nil {
+		return fmt.Errorf("read spool header: %w", err)
+	}
+
+	outHeader := append(append([]string(nil), header...), "AiBots")
+	writer := csvout.NewFor(out, cfg.OutPath)
+	if err := writer.WriteHeader(outHeader); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	strictDone, err := validateOutput("all", writer, outHeader, cfg.OutPath)
+	if err != nil {
+		return err
+	}
+	defer strictDone()
+
+	m := newMerger(verMap, cfg.UAPtrVerify, cfg.TrustSource, outHeader)
+	proj := newProjection(reader, header)
+	ua := colIndex(outHeader, "user_agent")
+	var (
+		rowsOut, tagged int64
+		row             []string
+	)
+	start := time.Now()
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rec, err := reader.NextRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read spool: %w", err)
+		}
+		row = append(proj.fill(rec.Fields(), row), "")
+		m.apply(row)
+
+		ai := "-"
+		if found := aibots.Detect(cell(row, ua)); len(found) > 0 {
+			ai = found[0]
+			tagged++
+		}
+		row[len(row)-1] = ai
+		if err := writer.WriteRow(row); err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+		rowsOut++
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	if err := strictDone(); err != nil {
+		return err
+	}
+	log.Printf("all done. out=%d patched=%d spoofed=%d ai_tagged=%d time=%s", rowsOut, m.patched, m.spoofed, tagged, time.Since(start))
+	return m.report(cfg.SpoofReport)
+}
+
+// colIndex: indeks kolone u header-u (-1 nema).
+func colIndex(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// cell / setCell: pristup koloni reda po indeksu iz colIndex (-1 se ignoriše).
+func cell(row []string, i int) string {
+	if i >= 0 && i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+func setCell(row []string, i int, v string) {
+	if i >= 0 && i < len(row) {
+		row[i] = v
+	}
+}
+
+// projection: izlazna kolona → kolona ulaza, razrešeno jednom po header-u
+// (csvin.NoCol: kolona ne postoji u ulazu, ostaje prazna).
+type projection []csvin.Col
+
+func newProjection(r *csvin.Reader, header []string) projection {
+	p := make(projection, len(header))
+	for i, h := range header {
+		p[i] = r.Col(h)
+	}
+	return p
+}
+
+// fill upisuje rec preslikan u izlazni header u out (append na out[:0]).
+func (p projection) fill(rec, out []string) []string {
+	out = out[:0]
+	for _, c := range p {
+		v := ""
+		if c >= 0 && int(c) < len(rec) {
+			v = rec[c]
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// withoutColumn: header bez date kolone (npr. AiBots kad se aibots pusti ponovo).
+func withoutColumn(header []string, name string) []string {
+	out := make([]string, 0, len(header))
+	for _, h := range header {
+		if !strings.EqualFold(strings.TrimSpace(h), name) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// outputHeader: BaseColumns + dodatne kolone ulaza (redosled ulaza).
+func outputHeader(inHeader []string) []string {
+	return append(schema.BaseHeader(), schema.ExtraColumns(inHeader)...)
+}
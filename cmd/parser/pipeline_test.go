@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"parser/internal/botdetector"
+	"parser/internal/jsonl"
+	"parser/internal/mapper"
+	"parser/internal/verifier"
+)
+
+const pipelineFake = `ptr:
+  66.249.66.1: [crawl-66-249-66-1.googlebot.com]
+  1.2.3.4: [crawl.googlebot.com.evil.net]
+  5.6.7.8: [crawl-5.googlebot.com]
+hosts:
+  crawl-66-249-66-1.googlebot.com: [66.249.66.1]
+  crawl-5.googlebot.com: [9.9.9.9]
+`
+
+// pipelineInput: Logpush JSONL sa više od jednog batch-a (512) redova, jednom
+// nevalidnom i jednom praznom linijom.
+func pipelineInput(n int) string {
+	ips := []string{"66.249.66.1", "1.2.3.4", "5.6.7.8", "8.8.4.4", "2001:db8::1"}
+	uas := []string{uaGooglebot, uaBrowser, "Mozilla/5.0 (compatible; GPTBot/1.0; +https://openai.com/gptbot)"}
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		switch i {
+		case 100:
+			b.WriteString("{nije json\n")
+		case 700:
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, `{"EdgeEndTimestamp":"2025-09-%02dT10:00:%02dZ","ClientIP":%q,"ClientRequestURI":"/p/%d","ClientRequestHost":"example.com","ClientRequestScheme":"https","ClientRequestMethod":"GET","EdgeResponseStatus":%d,"EdgeResponseBytes":%d,"ClientRequestUserAgent":%q,"Nested":{"a":%d}}`+"\n",
+			1+i%28, i%60, ips[i%len(ips)], i, []int{200, 404, 301}[i%3], 1000+i, uas[i%len(uas)], i)
+	}
+	return b.String()
+}
+
+// --stage all mora da da isti izlaz kao faze pokrenute jedna po jedna
+// (jsonl --jsonl-columns mapper --jsonl-ordered → normalize → enrich →
+// verify → merge → aibots), za svaki broj radnika.
+func TestPipelineMatchesStages(t *testing.T) {
+	if err := botdetector.InitFromFile(""); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	p := func(name string) string { return filepath.Join(dir, name) }
+	if err := os.WriteFile(p("fake.yaml"), []byte(pipelineFake), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p("in.jsonl"), []byte(pipelineInput(1300)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := verifier.OpenResolver("", p("fake.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	m := mapper.Default()
+
+	cols, err := parseColumnList("mapper", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jsonl.ConvertJSONLToCSVConcurrent(p("in.jsonl"), p("raw.csv"), jsonl.Options{
+		Workers: 3, TempDir: dir, Columns: cols, Ordered: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runNormalize(ctx, p("raw.csv"), p("norm.csv"), "auto", m, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := runEnrich(ctx, p("norm.csv"), p("final.csv"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := runVerify(ctx, res, verifyCacheOpts{}, p("norm.csv"), p("verified.csv"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMerge(ctx, p("final.csv"), p("verified.csv"), p("merged.csv"), false, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAIBots(ctx, p("merged.csv"), p("staged.csv")); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(p("staged.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(want, []byte("\n")); n != 1301 {
+		t.Fatalf("staged: %d linija, want 1301", n)
+	}
+
+	for _, w := range []int{1, 2, 8} {
+		out := p(fmt.Sprintf("all_%d.csv", w))
+		err := runPipeline(ctx, pipelineConfig{
+			InPath: p("in.jsonl"), OutPath: out, TempDir: dir, Workers: w,
+			Format: "auto", Mapper: m, Resolver: res, DNSWorkers: 3,
+		})
+		if err != nil {
+			t.Fatalf("workers=%d: %v", w, err)
+		}
+		got, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("workers=%d: --stage all izlaz se razlikuje od faza jedna po jedna", w)
+		}
+	}
+	spools, _ := filepath.Glob(p("pipeline_spool_*"))
+	if len(spools) != 0 {
+		t.Errorf("spool fajlovi nisu obrisani: %v", spools)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"parser/internal/csvout"
+	"parser/internal/iox"
+	"parser/internal/schema"
+)
+
+// ---------- --strict: tipska provera izlaznih redova (schema.Kind) ----------
+//
+// Postavlja se jednom iz flagova u main(); faze koje pišu schema redove
+// (accesslog, normalize, enrich, merge, aibots, all) zovu validateOutput
+// odmah posle WriteHeader.
+var (
+	strictMode    schema.Mode
+	strictRejects string // ModeReject; "" => <out>.rejects.csv
+)
+
+func rejectsPathFor(outPath string) string {
+	if strictRejects != "" {
+		return strictRejects
+	}
+	base := iox.TrimCompressExt(outPath)
+	for _, ext := range []string{".csv", ".parquet"} {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return base + ".rejects.csv"
+}
+
+// validateOutput kači validator na writer. done (posle writer.Flush) zatvara
+// rejects fajl i loguje brojače po koloni; može se pozvati više puta
+// (defer + eksplicitno na kraju faze).
+func validateOutput(stage string, w *csvout.Writer, header []string, outPath string) (done func() error, err error) {
+	if strictMode == schema.ModeOff {
+		return func() error { return nil }, nil
+	}
+	v := schema.NewValidator(header, strictMode)
+
+	var (
+		rf      *os.File
+		rw      *csvout.Writer
+		rejPath string
+	)
+	if strictMode == schema.ModeReject {
+		if iox.IsStdio(outPath) && strictRejects == "" {
+			return nil, fmt.Errorf("%s: --strict=reject with --out - requires --strict-rejects", stage)
+		}
+		rejPath = rejectsPathFor(outPath)
+		if rejPath == outPath || iox.IsStdio(rejPath) {
+			return nil, fmt.Errorf("%s: --strict-rejects must differ from --out and stdout", stage)
+		}
+		rf, err = os.Create(rejPath)
+		if err != nil {
+			return nil, fmt.Errorf("create rejects: %w", err)
+		}
+		rw = csvout.New(rf)
+		if err := rw.WriteHeader(header); err != nil {
+			_ = rf.Close()
+			return nil, fmt.Errorf("write rejects header: %w", err)
+		}
+	}
+	w.Validate(v, rw)
+
+	finished := false
+	return func() error {
+		if finished {
+			return nil
+		}
+		finished = true
+
+		viol := v.Violations()
+		cols := make([]string, 0, len(viol))
+		for c := range viol {
+			cols = append(cols, c)
+		}
+		sort.Strings(cols)
+		for _, c := range cols {
+			log.Printf("%s strict: column=%s kind=%s violations=%d", stage, c, schema.KindOf(c), viol[c])
+		}
+		log.Printf("%s strict: mode=%s columns_with_violations=%d rejected=%d", stage, strictMode, len(cols), v.Rejected())
+
+		if rf == nil {
+			return nil
+		}
+		ferr := rw.Flush()
+		if cerr := rf.Close(); ferr == nil {
+			ferr = cerr
+		}
+		if ferr != nil {
+			return fmt.Errorf("rejects: %w", ferr)
+		}
+		if v.Rejected() == 0 {
+			return os.Remove(rejPath)
+		}
+		log.Printf("%s strict: rejected rows written to %s", stage, rejPath)
+		return nil
+	}, nil
+}
@@ -11,7 +11,6 @@ import (
 
 	"parser/internal/botdetector"
 	"parser/internal/csvin"
-	"parser/internal/iox"
 	"parser/internal/verifier"
 )
 
@@ -21,7 +20,9 @@ var (
 	botsPath = flag.String("bots", "", "Optional path to bots config (JSON or YAML)")
 	stage    = flag.String("stage", "", "Stage to run: normalize | enrich | verify")
 	//plan     = flag.Bool("plan", false, "If true, show column plan and exit")
-	workers = flag.Int("workers", 15, "Number of parallel DNS workers for verify stage")
+	workers   = flag.Int("workers", 15, "Number of parallel DNS workers for verify stage")
+	dnsServer = flag.String("dns-server", "", "DNS server (host[:port]); default: system resolver")
+	dnsFake   = flag.String("dns-fake", "", "Offline resolver file (.json/.yaml with ptr/hosts tables)")
 )
 
 func main() {
@@ -38,7 +39,11 @@ func main() {
 	case "enrich":
 		runEnrich(ctx, *inPath, *outPath)
 	case "verify":
-		if err := runVerify(ctx, *inPath, *outPath, *botsPath, *workers); err != nil {
+		res, err := verifier.OpenResolver(*dnsServer, *dnsFake)
+		if err != nil {
+			log.Fatalf("verify error: %v", err)
+		}
+		if err := runVerify(ctx, res, *inPath, *outPath, *botsPath, *workers); err != nil {
 			log.Fatalf("verify error: %v", err)
 		}
 	default:
@@ -54,48 +59,39 @@ func runEnrich(ctx context.Context, inPath, outPath string) {
 	fmt.Println("enrich stage placeholder – implemented earlier")
 }
 
-func runVerify(ctx context.Context, inPath, outPath, botsPath string, workers int) error {
+func runVerify(ctx context.Context, res verifier.Resolver, inPath, outPath, botsPath string, workers int) error {
 	log.Printf("verify stage: reading unique IPs from %s", inPath)
 
 	// 1️⃣ Učitavanje bot pravila
 	if err := botdetector.InitFromFile(botsPath); err != nil {
-		log.Printf("warning: bot rules load failed: %v (using defaults)", err)
+		return err
 	}
 
 	// 2️⃣ Otvaranje normalized.csv i prikupljanje IP adresa
-	in, err := iox.OpenAuto(inPath)
+	reader, err := csvin.Open(inPath, csvin.Options{})
 	if err != nil {
 		return fmt.Errorf("open input: %w", err)
 	}
-	defer in.Close()
+	defer reader.Close()
 
-	reader := csvin.New(in, csvin.Options{Comma: ',', TrimSpace: true})
-	header, _, err := reader.Header()
-	if err != nil {
+	if _, _, err := reader.Header(); err != nil {
 		return fmt.Errorf("read header: %w", err)
 	}
-
-	colIdx := -1
-	for i, name := range header {
-		if name == "host_ip" {
-			colIdx = i
-			break
-		}
-	}
-	if colIdx == -1 {
+	ipCol := reader.Col("host_ip")
+	if ipCol == csvin.NoCol {
 		return fmt.Errorf("column 'host_ip' not found")
 	}
 
 	unique := make(map[string]struct{})
 	for {
-		row, err := reader.Next()
+		rec, err := reader.NextRecord()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			continue
+			return fmt.Errorf("read input: %w", err)
 		}
-		ip := strings.TrimSpace(row["host_ip"])
+		ip := strings.TrimSpace(rec.At(ipCol))
 		if ip != "" {
 			unique[ip] = struct{}{}
 		}
@@ -124,7 +120,7 @@ func runVerify(ctx context.Context, inPath, outPath, botsPath string, workers in
 		}
 	}()
 
-	results, err := verifier.VerifyIPs(ctx, ips, workers, 3*time.Second, progressChan)
+	results, err := verifier.VerifyIPs(ctx, res, ips, workers, 3*time.Second, progressChan)
 	close(progressChan)
 	if err != nil {
 		return fmt.Errorf("verify: %w", err)
@@ -0,0 +1,437 @@
+package accesslog
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Predefinisani formati (--log-format common|combined|nginx).
+const (
+	Common        = `%h %l %u %t "%r" %>s %b`
+	Combined      = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`
+	NginxCombined = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+)
+
+// Interna imena polja u koja se slivaju Apache direktive i nginx promenljive.
+const (
+	fIP        = "ip"
+	fTimeLocal = "time_local" // 02/Jan/2006:15:04:05 -0700 (sa ili bez [])
+	fTimeISO   = "time_iso"   // RFC3339
+	fMsec      = "msec"       // unix sekunde sa milisekundama
+	fRequest   = "request"    // "GET /p?q HTTP/1.1"
+	fMethod    = "method"
+	fURI       = "uri"   // path + query
+	fPath      = "path"  // samo path
+	fQuery     = "query" // sa ili bez vodećeg '?'
+	fStatus    = "status"
+	fBytes     = "bytes"
+	fReferer   = "referer"
+	fUA        = "ua"
+	fHost      = "host"
+	fScheme    = "scheme"
+)
+
+// nginx promenljive → interno polje; nepoznate se parsiraju i ignorišu.
+var nginxVars = map[string]string{
+	"remote_addr":     fIP,
+	"time_local":      fTimeLocal,
+	"time_iso8601":    fTimeISO,
+	"msec":            fMsec,
+	"request":         fRequest,
+	"request_method":  fMethod,
+	"request_uri":     fURI,
+	"uri":             fPath,
+	"document_uri":    fPath,
+	"args":            fQuery,
+	"query_string":    fQuery,
+	"status":          fStatus,
+	"body_bytes_sent": fBytes,
+	"bytes_sent":      fBytes,
+	"http_referer":    fReferer,
+	"http_user_agent": fUA,
+	"host":            fHost,
+	"http_host":       fHost,
+	"server_name":     fHost,
+	"scheme":          fScheme,
+}
+
+// Apache LogFormat direktive → interno polje.
+var apacheDirectives = map[string]string{
+	"h": fIP,
+	"a": fIP,
+	"t": fTimeLocal,
+	"r": fRequest,
+	"m": fMethod,
+	"U": fPath,
+	"q": fQuery,
+	"s": fStatus,
+	"b": fBytes,
+	"B": fBytes,
+	"O": fBytes,
+	"v": fHost,
+	"V": fHost,
+}
+
+// Apache %{Header}i zaglavlja (case-insensitive).
+var apacheHeaders = map[string]string{
+	"referer":    fReferer,
+	"user-agent": fUA,
+	"host":       fHost,
+}
+
+type token struct {
+	lit   string // literal (ako field == "")
+	field string // interno ime ili "" za literal; nepoznata polja dobijaju "?<ime>"
+}
+
+// Parser parsira linije access loga po jednom formatu.
+type Parser struct {
+	tokens []token
+	loc    *time.Location
+}
+
+// Compile prevodi format u Parser. format je "common", "combined", "nginx"
+// ili custom string: nginx log_format ($promenljive) ili Apache LogFormat
+// (%direktive). loc je zona za izlazne timestampe (nil → UTC); offset iz
+// same linije se uvek poštuje.
+func Compile(format string, loc *time.Location) (*Parser, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "combined":
+		format = Combined
+	case "common":
+		format = Common
+	case "nginx", "nginx-combined":
+		format = NginxCombined
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	var (
+		toks []token
+		err  error
+	)
+	if strings.Contains(format, "$") {
+		toks, err = tokenizeNginx(format)
+	} else {
+		toks, err = tokenizeApache(format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hasField := false
+	for i, t := range toks {
+		if t.field == "" {
+			continue
+		}
+		hasField = true
+		if i > 0 && toks[i-1].field != "" {
+			return nil, fmt.Errorf("accesslog: fields without separator in format %q", format)
+		}
+	}
+	if !hasField {
+		return nil, fmt.Errorf("accesslog: format %q has no fields", format)
+	}
+	return &Parser{tokens: toks, loc: loc}, nil
+}
+
+func tokenizeNginx(format string) ([]token, error) {
+	var toks []token
+	var lit strings.Builder
+	for i := 0; i < len(format); {
+		if format[i] != '$' {
+			lit.WriteByte(format[i])
+			i++
+			continue
+		}
+		j := i + 1
+		braced := j < len(format) && format[j] == '{'
+		if braced {
+			j++
+		}
+		k := j
+		for k < len(format) && (isAlnum(format[k]) || format[k] == '_') {
+			k++
+		}
+		if k == j {
+			return nil, fmt.Errorf("accesslog: bad variable at offset %d in %q", i, format)
+		}
+		name := format[j:k]
+		if braced {
+			if k >= len(format) || format[k] != '}' {
+				return nil, fmt.Errorf("accesslog: unterminated ${%s in %q", name, format)
+			}
+			k++
+		}
+		if lit.Len() > 0 {
+			toks = append(toks, token{lit: lit.String()})
+			lit.Reset()
+		}
+		f, ok := nginxVars[name]
+		if !ok {
+			f = "?" + name
+		}
+		toks = append(toks, token{field: f})
+		i = k
+	}
+	if lit.Len() > 0 {
+		toks = append(toks, token{lit: lit.String()})
+	}
+	return toks, nil
+}
+
+func tokenizeApache(format string) ([]token, error) {
+	var toks []token
+	var lit strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c != '%' {
+			lit.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			lit.WriteByte('%')
+			i += 2
+			continue
+		}
+		j := i + 1
+		// modifikatori: %>s, %<s, %400,501{...}i, %!200s
+		for j < len(format) && strings.IndexByte("<>!,0123456789", format[j]) >= 0 {
+			j++
+		}
+		mod := format[i+1 : j]
+		arg := ""
+		if j < len(format) && format[j] == '{' {
+			end := strings.IndexByte(format[j:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("accesslog: unterminated %%{ in %q", format)
+			}
+			arg = format[j+1 : j+end]
+			j += end + 1
+		}
+		if j >= len(format) {
+			return nil, fmt.Errorf("accesslog: dangling %% in %q", format)
+		}
+		d := string(format[j])
+		j++
+		if lit.Len() > 0 {
+			toks = append(toks, token{lit: lit.String()})
+			lit.Reset()
+		}
+		var f string
+		switch {
+		case d == "i" && arg != "":
+			f = apacheHeaders[strings.ToLower(arg)]
+		case d == "t" && arg != "":
+			// %{format}t — strftime format, ne parsiramo
+		case d == "s" && strings.Contains(mod, ">"):
+			f = fStatus
+		default:
+			f = apacheDirectives[d]
+		}
+		if f == "" {
+			f = "?" + d + arg
+		}
+		toks = append(toks, token{field: f})
+		i = j
+	}
+	if lit.Len() > 0 {
+		toks = append(toks, token{lit: lit.String()})
+	}
+	return toks, nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// ErrNoMatch: linija ne odgovara formatu.
+var ErrNoMatch = errors.New("line does not match log format")
+
+// split vraća vrednosti polja po internom imenu ("-" → "").
+func (p *Parser) split(line string) (map[string]string, error) {
+	line = strings.TrimRight(line, "\r\n")
+	vals := make(map[string]string, 16)
+	pos := 0
+	for i, t := range p.tokens {
+		if t.field == "" {
+			if !strings.HasPrefix(line[pos:], t.lit) {
+				return nil, fmt.Errorf("%w: expected %q at offset %d", ErrNoMatch, t.lit, pos)
+			}
+			pos += len(t.lit)
+			continue
+		}
+		var v string
+		if i+1 >= len(p.tokens) {
+			v = strings.TrimRight(line[pos:], " \t")
+			pos = len(line)
+		} else {
+			next := p.tokens[i+1].lit
+			quoted := i > 0 && strings.HasSuffix(p.tokens[i-1].lit, `"`) && strings.HasPrefix(next, `"`)
+			end := indexDelim(line[pos:], next, quoted)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: missing %q after offset %d", ErrNoMatch, next, pos)
+			}
+			v = line[pos : pos+end]
+			pos += end
+		}
+		if strings.IndexByte(v, '\\') >= 0 {
+			v = unescape(v)
+		}
+		if v == "-" {
+			v = ""
+		}
+		if _, dup := vals[t.field]; !dup || v != "" {
+			vals[t.field] = v
+		}
+	}
+	return vals, nil
+}
+
+// indexDelim traži delim u s; u navodnicima preskače \-escape-ovane znake
+// (Apache \" i nginx \x22 u User-Agent-u).
+func indexDelim(s, delim string, quoted bool) int {
+	if !quoted {
+		return strings.Index(s, delim)
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], delim) {
+			return i
+		}
+	}
+	return -1
+}
+
+// unescape: \" \\ i \xHH iz Apache/nginx escape-ovanja.
+func unescape(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch n := s[i+1]; {
+		case n == 'x' && i+3 < len(s):
+			if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+			b.WriteByte('\\')
+		case n == '"' || n == '\\':
+			b.WriteByte(n)
+			i++
+		default:
+			b.WriteByte('\\')
+		}
+	}
+	return b.String()
+}
+
+// Parse vraća polja jedne linije pod Cloudflare imenima (ClientIP,
+// EdgeEndTimestamp, ClientRequestURI …), tako da mapper.MapToCSV daje
+// isti BaseColumns red kao za JSONL ulaz. EdgeEndTimestamp je RFC3339 u
+// zoni parsera. Prazna linija vraća (nil, nil).
+func (p *Parser) Parse(line string) (map[string]string, error) {
+	if strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+	v, err := p.split(line)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, 12)
+	out["ClientIP"] = v[fIP]
+
+	ts, err := p.timestamp(v)
+	if err != nil {
+		return nil, err
+	}
+	if !ts.IsZero() {
+		out["EdgeEndTimestamp"] = ts.In(p.loc).Format(time.RFC3339)
+	}
+
+	method, uri := v[fMethod], v[fURI]
+	if req := v[fRequest]; req != "" {
+		// "GET /p?q HTTP/1.1" — protokol može da fali (HTTP/0.9)
+		parts := strings.Fields(req)
+		if len(parts) >= 2 {
+			if method == "" {
+				method = parts[0]
+			}
+			if uri == "" {
+				uri = parts[1]
+			}
+		}
+	}
+	path := v[fPath]
+	if uri == "" && path != "" {
+		uri = path
+		if q := strings.TrimPrefix(v[fQuery], "?"); q != "" {
+			uri += "?" + q
+		}
+	}
+	if path == "" && uri != "" {
+		if u, err := url.Parse(uri); err == nil {
+			path = u.Path
+		}
+	}
+
+	status := v[fStatus]
+	if status != "" {
+		if n, err := strconv.Atoi(status); err != nil || n < 100 || n > 999 {
+			return nil, fmt.Errorf("%w: bad status %q", ErrNoMatch, status)
+		}
+	}
+	size := v[fBytes]
+	if size == "" && status != "" {
+		size = "0" // Apache %b piše "-" za 0 bajtova
+	}
+
+	out["ClientRequestMethod"] = method
+	out["ClientRequestURI"] = uri
+	out["ClientRequestPath"] = path
+	out["ClientRequestHost"] = v[fHost]
+	out["ClientRequestScheme"] = strings.ToLower(v[fScheme])
+	out["EdgeResponseStatus"] = status
+	out["EdgeResponseBytes"] = size
+	out["ClientRequestReferer"] = v[fReferer]
+	out["ClientRequestUserAgent"] = v[fUA]
+	return out, nil
+}
+
+func (p *Parser) timestamp(v map[string]string) (time.Time, error) {
+	if s := v[fTimeISO]; s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: bad time %q", ErrNoMatch, s)
+		}
+		return t, nil
+	}
+	if s := strings.Trim(v[fTimeLocal], "[]"); s != "" {
+		t, err := time.Parse("02/Jan/2006:15:04:05 -0700", s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: bad time %q", ErrNoMatch, s)
+		}
+		return t, nil
+	}
+	if s := v[fMsec]; s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: bad msec %q", ErrNoMatch, s)
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC(), nil
+	}
+	return time.Time{}, nil
+}
@@ -0,0 +1,98 @@
+package accesslog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name, format, line string
+		want               map[string]string // samo polja koja se proveravaju
+	}{
+		{
+			name:   "combined",
+			format: "combined",
+			line:   `66.249.66.1 - - [01/Sep/2025:12:00:00 +0200] "GET /p?a=1 HTTP/1.1" 200 5117 "https://example.com/" "Mozilla/5.0 (compatible; Googlebot/2.1)"`,
+			want: map[string]string{
+				"ClientIP": "66.249.66.1", "EdgeEndTimestamp": "2025-09-01T10:00:00Z",
+				"ClientRequestMethod": "GET", "ClientRequestURI": "/p?a=1", "ClientRequestPath": "/p",
+				"EdgeResponseStatus": "200", "EdgeResponseBytes": "5117",
+				"ClientRequestReferer": "https://example.com/", "ClientRequestUserAgent": "Mozilla/5.0 (compatible; Googlebot/2.1)",
+			},
+		},
+		{
+			name:   "common, %b je -",
+			format: "common",
+			line:   `1.2.3.4 - frank [01/Sep/2025:10:00:00 +0000] "HEAD / HTTP/1.0" 304 -`,
+			want: map[string]string{
+				"ClientIP": "1.2.3.4", "ClientRequestMethod": "HEAD", "ClientRequestURI": "/",
+				"EdgeResponseStatus": "304", "EdgeResponseBytes": "0",
+			},
+		},
+		{
+			name:   "nginx combined",
+			format: "nginx",
+			line:   `2001:db8::1 - - [01/Sep/2025:10:00:00 +0000] "GET /x HTTP/2.0" 404 12 "-" "curl/8.0"`,
+			want: map[string]string{
+				"ClientIP": "2001:db8::1", "ClientRequestURI": "/x", "EdgeResponseStatus": "404",
+				"ClientRequestUserAgent": "curl/8.0",
+			},
+		},
+		{
+			name:   "custom nginx sa host i scheme",
+			format: `$remote_addr [$time_iso8601] $scheme://$host $request_uri $status`,
+			line:   `1.2.3.4 [2025-09-01T10:00:00+00:00] HTTPS://example.com /a?b=c 200`,
+			want: map[string]string{
+				"ClientIP": "1.2.3.4", "EdgeEndTimestamp": "2025-09-01T10:00:00Z",
+				"ClientRequestScheme": "https", "ClientRequestHost": "example.com",
+				"ClientRequestURI": "/a?b=c", "ClientRequestPath": "/a",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := Compile(tc.format, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Parse(tc.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, w := range tc.want {
+				if got[k] != w {
+					t.Errorf("%s = %q, want %q", k, got[k], w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	p, err := Compile("combined", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row, err := p.Parse("   "); row != nil || err != nil {
+		t.Errorf("prazna linija: (%v, %v), want (nil, nil)", row, err)
+	}
+	for _, line := range []string{
+		`1.2.3.4 - - [01/Sep/2025:10:00:00 +0000] "GET / HTTP/1.1" abc 1 "-" "ua"`,
+		`1.2.3.4 - - [nije vreme] "GET / HTTP/1.1" 200 1 "-" "ua"`,
+		`nešto sasvim drugo`,
+	} {
+		if _, err := p.Parse(line); !errors.Is(err, ErrNoMatch) {
+			t.Errorf("Parse(%q) err = %v, want ErrNoMatch", line, err)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, f := range []string{"samo tekst", "%h%u", "$remote_addr$status"} {
+		if _, err := Compile(f, nil); err == nil {
+			t.Errorf("Compile(%q): očekivana greška", f)
+		}
+	}
+}
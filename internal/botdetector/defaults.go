@@ -0,0 +1,45 @@
+package botdetector
+
+// defaultRules se koriste kada --bots nije zadat. --bots fajl ih zamenjuje
+// u celosti (bez spajanja).
+// Redosled je bitan: MatchUA vraća prvo pravilo koje se poklopi.
+var defaultRules = []Rule{
+	// search
+	{Name: "Googlebot", UA: []string{"googlebot"}, PTRSuffixes: []string{"googlebot.com", "google.com"}, Category: CategorySearch, Operator: "Google"},
+	{Name: "Bingbot", UA: []string{"bingbot"}, PTRSuffixes: []string{"search.msn.com"}, Category: CategorySearch, Operator: "Microsoft"},
+	{Name: "DuckDuckBot", UA: []string{"duckduckbot"}, PTRSuffixes: []string{"duckduckgo.com"}, Category: CategorySearch, Operator: "DuckDuckGo"},
+	{Name: "YandexBot", UA: []string{"yandex(bot)?"}, PTRSuffixes: []string{"yandex.ru", "yandex.net", "yandex.com"}, Category: CategorySearch, Operator: "Yandex"},
+	{Name: "Applebot", UA: []string{"applebot"}, PTRSuffixes: []string{"applebot.apple.com"}, Category: CategorySearch, Operator: "Apple"},
+
+	// seo
+	{Name: "AhrefsBot", UA: []string{"ahrefsbot"}, PTRSuffixes: []string{"ahrefs.com", "ahrefs.net"}, Category: CategorySEO, Operator: "Ahrefs"},
+	{Name: "SemrushBot", UA: []string{"semrush(bot)?"}, PTRSuffixes: []string{"semrush.com"}, Category: CategorySEO, Operator: "Semrush"},
+
+	// social
+	{Name: "LinkedInBot", UA: []string{"linkedin(bot)?"}, PTRSuffixes: []string{"linkedin.com"}, Category: CategorySocial, Operator: "LinkedIn"},
+	{Name: "FacebookBot", UA: []string{"facebookexternalhit|facebot"}, PTRSuffixes: []string{"facebook.com", "fbsv.net", "tfbnw.net"}, Category: CategorySocial, Operator: "Meta"},
+	{Name: "TwitterBot", UA: []string{"twitter(bot)?|TweetmemeBot"}, PTRSuffixes: []string{"twitter.com", "twttr.com"}, Category: CategorySocial, Operator: "X"},
+
+	// ai (ranije hardkodovano u aibots)
+	{Name: "OAI-SearchBot", UA: []string{`OAI-SearchBot`}, Category: CategoryAI, Operator: "OpenAI"},
+	{Name: "GPTBot", UA: []string{`GPTBot`}, Category: CategoryAI, Operator: "OpenAI"},
+	{Name: "ChatGPT-User", UA: []string{`ChatGPT-User`}, Category: CategoryAI, Operator: "OpenAI"},
+	{Name: "PerplexityBot", UA: []string{`PerplexityBot`}, Category: CategoryAI, Operator: "Perplexity"},
+	{Name: "Perplexity-User", UA: []string{`Perplexity-User`}, Category: CategoryAI, Operator: "Perplexity"},
+	{Name: "Ai2Bot-Dolma", UA: []string{`Ai2Bot-Dolma`}, Category: CategoryAI, Operator: "Allen Institute for AI"},
+	{Name: "AI2Bot", UA: []string{`AI2Bot`}, Category: CategoryAI, Operator: "Allen Institute for AI"},
+	{Name: "Amazonbot", UA: []string{`Amazonbot`}, PTRSuffixes: []string{"crawl.amazonbot.amazon"}, Category: CategoryAI, Operator: "Amazon"},
+	{Name: "anthropic-ai", UA: []string{`anthropic-ai`, `antropic-ai`}, Category: CategoryAI, Operator: "Anthropic"},
+	{Name: "Claude-Web", UA: []string{`Claude-Web`}, Category: CategoryAI, Operator: "Anthropic"},
+	{Name: "ClaudeBot", UA: []string{`ClaudeBot`}, Category: CategoryAI, Operator: "Anthropic"},
+	{Name: "Claude-SearchBot", UA: []string{`Claude-SearchBot`}, Category: CategoryAI, Operator: "Anthropic"},
+	{Name: "Claude-User", UA: []string{`Claude-User`}, Category: CategoryAI, Operator: "Anthropic"},
+	{Name: "cohere-ai", UA: []string{`cohere-ai`}, Category: CategoryAI, Operator: "Cohere"},
+	{Name: "Google-Extended", UA: []string{`Google-Extended`}, Category: CategoryAI, Operator: "Google"},
+	{Name: "Google-CloudVertexBot", UA: []string{`Google-CloudVertexBot`}, Category: CategoryAI, Operator: "Google"},
+}
+
+func defaults() *Detector {
+	d, _ := compile(defaultRules, "")
+	return d
+}
@@ -1,35 +1,88 @@
 package botdetector
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"parser/internal/iprange"
+)
+
+// Kategorije botova (Rule.Category).
+const (
+	CategorySearch     = "search"
+	CategorySEO        = "seo"
+	CategoryAI         = "ai"
+	CategorySocial     = "social"
+	CategoryMonitoring = "monitoring"
 )
 
+// Rule je jedan bot u bots fajlu (JSON ili YAML lista):
+//
+//   - name: Googlebot
+//     ua: ["googlebot"]
+//     ptr_suffixes: ["googlebot.com", "google.com"]
+//     cidr_files: ["ranges/googlebot.json"]
+//     category: search
+//     operator: Google
+//
+// Regex je stari format (jedan regex i za UA i za PTR); koristi se samo
+// ako UA odnosno PTRSuffixes nisu zadati.
 type Rule struct {
 	Name  string `json:"name" yaml:"name"`
-	Regex string `json:"regex" yaml:"regex"`
+	Regex string `json:"regex,omitempty" yaml:"regex,omitempty"`
+	// UA: regexi nad User-Agent-om (case-insensitive).
+	UA []string `json:"ua,omitempty" yaml:"ua,omitempty"`
+	// PTRSuffixes: dozvoljeni domeni PTR hostname-a ("googlebot.com" pokriva
+	// "crawl-1-2-3-4.googlebot.com", ali ne "googlebot.com.evil.net").
+	PTRSuffixes []string `json:"ptr_suffixes,omitempty" yaml:"ptr_suffixes,omitempty"`
+	// CIDRFiles: lokalne liste zvaničnih IP opsega bota (npr. googlebot.json).
+	// Relativne putanje se računaju od direktorijuma bots fajla.
+	CIDRFiles []string `json:"cidr_files,omitempty" yaml:"cidr_files,omitempty"`
+	Category  string   `json:"category,omitempty" yaml:"category,omitempty"`
+	Operator  string   `json:"operator,omitempty" yaml:"operator,omitempty"`
+}
+
+// Bot je rezultat match-a: kanonsko ime + metapodaci iz pravila.
+type Bot struct {
+	Name     string
+	Category string
+	Operator string
+	// Verifiable: pravilo ima PTR sufikse (ili legacy regex) ili CIDR liste,
+	// pa se tvrdnja iz UA može proveriti po IP-u.
+	Verifiable bool
 }
 
 type compiled struct {
-	name string
-	re   *regexp.Regexp
+	bot      Bot
+	ua       []*regexp.Regexp
+	ptrRe    *regexp.Regexp // legacy Regex, samo kad nema ptr_suffixes
+	suffixes []string
 }
 
 type Detector struct {
 	rules []compiled
+	ips   *iprange.Table
+	// fp: sha256 pravila + učitanih CIDR opsega (Fingerprint)
+	fp string
 }
 
 var global *Detector
 
 // InitFromFile initializes global detector from a JSON or YAML file.
-// If path == "", or loading fails, it falls back to defaults.
+// If path == "", the built-in defaults are used. A file replaces the
+// defaults entirely (it is not merged with them), so it must list every
+// bot to detect, including the category: ai ones used by aibots.
+// A file that cannot be loaded is an error; the detector is left unchanged.
 func InitFromFile(path string) error {
 	if path == "" {
 		global = defaults()
@@ -37,8 +90,7 @@ func InitFromFile(path string) error {
 	}
 	d, err := loadFromFile(path)
 	if err != nil {
-		global = defaults()
-		return fmt.Errorf("botdetector: %w (fallback to defaults)", err)
+		return fmt.Errorf("botdetector: load %s: %w", path, err)
 	}
 	global = d
 	return nil
@@ -66,55 +118,188 @@ func loadFromFile(path string) (*Detector, error) {
 	if len(rules) == 0 {
 		return nil, errors.New("no rules found in bots file")
 	}
-	return compile(rules)
+	return compile(rules, filepath.Dir(path))
 }
 
-func compile(rules []Rule) (*Detector, error) {
+func compileRegex(rx string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(rx, "(?i)") {
+		rx = "(?i)" + rx
+	}
+	return regexp.Compile(rx)
+}
+
+func compile(rules []Rule, baseDir string) (*Detector, error) {
 	cs := make([]compiled, 0, len(rules))
 	for _, r := range rules {
-		rx := r.Regex
-		if rx == "" {
-			continue
+		if strings.TrimSpace(r.Name) == "" {
+			return nil, errors.New("rule without name")
+		}
+		cat := strings.ToLower(strings.TrimSpace(r.Category))
+		switch cat {
+		case "", CategorySearch, CategorySEO, CategoryAI, CategorySocial, CategoryMonitoring:
+		default:
+			return nil, fmt.Errorf("rule %q: unknown category %q", r.Name, r.Category)
+		}
+		c := compiled{bot: Bot{Name: r.Name, Category: cat, Operator: r.Operator}}
+
+		uas := r.UA
+		if len(uas) == 0 && r.Regex != "" {
+			uas = []string{r.Regex}
 		}
-		if !strings.HasPrefix(rx, "(?i)") {
-			rx = "(?i)" + rx
+		for _, rx := range uas {
+			if rx == "" {
+				continue
+			}
+			re, err := compileRegex(rx)
+			if err != nil {
+				return nil, fmt.Errorf("compile %q: %w", r.Name, err)
+			}
+			c.ua = append(c.ua, re)
 		}
-		re, err := regexp.Compile(rx)
-		if err != nil {
-			return nil, fmt.Errorf("compile %q: %w", r.Name, err)
+
+		for _, s := range r.PTRSuffixes {
+			s = strings.ToLower(strings.Trim(strings.TrimSpace(s), "."))
+			if s != "" {
+				c.suffixes = append(c.suffixes, s)
+			}
+		}
+		if len(c.suffixes) == 0 && r.Regex != "" {
+			re, err := compileRegex(r.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("compile %q: %w", r.Name, err)
+			}
+			c.ptrRe = re
 		}
-		cs = append(cs, compiled{name: r.Name, re: re})
+
+		c.bot.Verifiable = len(c.suffixes) > 0 || c.ptrRe != nil || len(r.CIDRFiles) > 0
+		if len(c.ua) == 0 && !c.bot.Verifiable {
+			continue
+		}
+		cs = append(cs, c)
 	}
 	if len(cs) == 0 {
-		return nil, errors.New("no valid regex rules compiled")
+		return nil, errors.New("no valid rules compiled")
+	}
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(rules); err != nil {
+		return nil, err
 	}
-	return &Detector{rules: cs}, nil
+	ips, err := loadCIDRs(rules, baseDir, h)
+	if err != nil {
+		return nil, err
+	}
+	return &Detector{rules: cs, ips: ips, fp: hex.EncodeToString(h.Sum(nil))}, nil
 }
 
-func defaults() *Detector {
-	d, _ := compile([]Rule{
-		{Name: "Googlebot", Regex: "googlebot"},
-		{Name: "Bingbot", Regex: "bingbot"},
-		{Name: "DuckDuckBot", Regex: "duckduckbot"},
-		{Name: "AhrefsBot", Regex: "ahrefsbot"},
-		{Name: "SemrushBot", Regex: "semrush(bot)?"},
-		{Name: "YandexBot", Regex: "yandex(bot)?"},
-		{Name: "LinkedInBot", Regex: "linkedin(bot)?|LinkedInBot"},
-		{Name: "FacebookBot", Regex: "facebookexternalhit|facebot"},
-		{Name: "TwitterBot", Regex: "twitter(bot)?|TweetmemeBot"},
-	})
-	return d
+// loadCIDRs učitava cidr_files; opsezi se upisuju i u h (Fingerprint), pa
+// izmena liste bez izmene bots fajla takođe menja otisak.
+func loadCIDRs(rules []Rule, baseDir string, h io.Writer) (*iprange.Table, error) {
+	t := iprange.New()
+	for _, r := range rules {
+		for _, f := range r.CIDRFiles {
+			if !filepath.IsAbs(f) && baseDir != "" {
+				f = filepath.Join(baseDir, f)
+			}
+			prefixes, err := iprange.LoadFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("cidr list %q for %q: %w", f, r.Name, err)
+			}
+			for _, p := range prefixes {
+				t.Insert(p, r.Name)
+				fmt.Fprintf(h, "%s %s\n", r.Name, p)
+			}
+		}
+	}
+	return t, nil
 }
 
-// Match returns (name, true) if string matches a rule, else ("", false).
-func Match(s string) (string, bool) {
+func get() *Detector {
 	if global == nil {
 		global = defaults()
 	}
-	for _, c := range global.rules {
-		if c.re.MatchString(s) {
-			return c.name, true
+	return global
+}
+
+// MatchUA returns the first rule whose UA pattern matches the user agent.
+func MatchUA(ua string) (Bot, bool) {
+	if ua == "" {
+		return Bot{}, false
+	}
+	for _, c := range get().rules {
+		for _, re := range c.ua {
+			if re.MatchString(ua) {
+				return c.bot, true
+			}
+		}
+	}
+	return Bot{}, false
+}
+
+// MatchUAAll returns every rule (in file order) whose UA pattern matches.
+func MatchUAAll(ua string) []Bot {
+	if ua == "" {
+		return nil
+	}
+	var out []Bot
+	for _, c := range get().rules {
+		for _, re := range c.ua {
+			if re.MatchString(ua) {
+				out = append(out, c.bot)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// MatchPTR returns the rule whose allowed PTR domain suffix covers host.
+// Rules without ptr_suffixes fall back to the legacy regex.
+func MatchPTR(host string) (Bot, bool) {
+	h := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if h == "" {
+		return Bot{}, false
+	}
+	for _, c := range get().rules {
+		for _, s := range c.suffixes {
+			if h == s || strings.HasSuffix(h, "."+s) {
+				return c.bot, true
+			}
+		}
+		if c.ptrRe != nil && c.ptrRe.MatchString(h) {
+			return c.bot, true
 		}
 	}
-	return "", false
+	return Bot{}, false
+}
+
+// Lookup returns the rule metadata for a canonical bot name.
+func Lookup(name string) (Bot, bool) {
+	for _, c := range get().rules {
+		if strings.EqualFold(c.bot.Name, name) {
+			return c.bot, true
+		}
+	}
+	return Bot{}, false
+}
+
+// MatchIP returns (name, true) if ip falls inside a published CIDR range
+// of some bot (longest prefix wins), else ("", false).
+func MatchIP(ip string) (string, bool) {
+	d := get()
+	if d.ips == nil || d.ips.Len() == 0 {
+		return "", false
+	}
+	return d.ips.LookupString(ip)
+}
+
+// HasCIDRs reports whether any CIDR lists were loaded.
+func HasCIDRs() bool {
+	d := get()
+	return d.ips != nil && d.ips.Len() > 0
+}
+
+// Fingerprint: otisak učitanih pravila (sadržaj pravila + CIDR opsezi).
+// dnscache ga čuva uz presude da keš ne preživi izmenu pravila.
+func Fingerprint() string {
+	return get().fp
 }
@@ -1,38 +1,137 @@
+// Package csvin je jedini CSV/Parquet čitač u repou (parser faze, verifier,
+// geninsert, ingest), pa se isti fajl svuda parsira isto:
+//
+//   - gzip/zstd ulaz i stdin ("-") preko iox.OpenAuto, .parquet preko parquetio
+//   - UTF-8 BOM se skida sa prve kolone header-a
+//   - imena kolona: tačno ime, pa case-insensitive (Col, ColAny)
+//   - LazyQuotes i TrimLeadingSpace su podrazumevani (Options.StrictQuotes/KeepSpace)
+//   - broj polja po redu se ne proverava (FieldsPerRecord=-1): kolona koja
+//     nedostaje u kraćem redu je "" (Next, Record.At)
+//   - red sa greškom (csv.ParseError, npr. navodnici uz StrictQuotes) se
+//     preskače, broji (Stats) i prijavljuje kroz Options.OnBadRow; ostale
+//     greške (I/O, Parquet) prekidaju čitanje
 package csvin
 
 import (
 	"bufio"
 	"encoding/csv"
+	"errors"
 	"io"
 	"strings"
+
+	"parser/internal/iox"
+	"parser/internal/parquetio"
 )
 
+// records: *csv.Reader ili *parquetio.Reader
+type records interface {
+	Read() ([]string, error)
+}
+
 type Reader struct {
-	cr     *csv.Reader
+	cr     records
+	closer io.Closer // Open
+	reuse  bool      // cr vraća isti slice za svaki red (csv ReuseRecord)
+	onBad  func(err error)
+
 	header []string
-	index  map[string]int
+	index  map[string]int // TrimSpace(ime) → indeks
+	lower  map[string]int // isto, lowercase (case-insensitive lookup)
 	inited bool
+
+	rows, bad int64
 }
 
+// Options: nulta vrednost je podrazumevano ponašanje za sve faze.
 type Options struct {
-	Comma      rune
-	Comment    rune
-	LazyQuotes bool
-	TrimSpace  bool
+	Comma        rune // 0 → ','
+	Comment      rune
+	StrictQuotes bool // isključi LazyQuotes (" usred polja bez navodnika je greška)
+	KeepSpace    bool // ne skidaj vodeće razmake u poljima
+
+	// Columns: samo Parquet — čitaj samo ove kolone (case-insensitive;
+	// nepostojeće se preskaču). nil = sve kolone.
+	Columns []string
+
+	// OnBadRow se poziva za svaki preskočen red (err je *csv.ParseError sa
+	// brojem linije). Broj preskočenih je u Stats().Bad i bez callback-a.
+	OnBadRow func(err error)
+}
+
+// Stats: brojači redova (Rows = vraćeni redovi, Bad = preskočeni).
+type Stats struct {
+	Rows, Bad int64
+}
+
+// Open otvara CSV (gzip/zstd preko iox.OpenAuto, "-" = stdin) ili Parquet
+// (.parquet) fajl. Reader treba zatvoriti sa Close.
+func Open(path string, opt Options) (*Reader, error) {
+	if iox.IsParquet(path) {
+		cols, err := parquetColumns(path, opt.Columns)
+		if err != nil {
+			return nil, err
+		}
+		pr, err := parquetio.Open(path, cols)
+		if err != nil {
+			return nil, err
+		}
+		r := &Reader{cr: pr, closer: pr, onBad: opt.OnBadRow}
+		r.setHeader(pr.Header())
+		return r, nil
+	}
+	f, err := iox.OpenAuto(path)
+	if err != nil {
+		return nil, err
+	}
+	r := New(f, opt)
+	r.closer = f
+	return r, nil
+}
+
+// parquetColumns: opt.Columns → stvarna imena kolona fajla (case-insensitive).
+func parquetColumns(path string, want []string) ([]string, error) {
+	if want == nil {
+		return nil, nil
+	}
+	all, err := parquetio.Columns(path)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(want))
+	for _, c := range want {
+		set[strings.ToLower(strings.TrimSpace(c))] = true
+	}
+	sel := []string{} // nijedna tražena kolona ne postoji: ne čitaj ništa
+	for _, c := range all {
+		if set[strings.ToLower(c)] {
+			sel = append(sel, c)
+		}
+	}
+	return sel, nil
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
 }
 
 func New(r io.Reader, opt Options) *Reader {
 	br := bufio.NewReaderSize(r, 1<<20)
 	cr := csv.NewReader(br)
+	cr.Comma = ','
 	if opt.Comma != 0 {
 		cr.Comma = opt.Comma
 	}
 	if opt.Comment != 0 {
 		cr.Comment = opt.Comment
 	}
-	cr.LazyQuotes = opt.LazyQuotes
-	cr.TrimLeadingSpace = opt.TrimSpace
-	return &Reader{cr: cr}
+	cr.LazyQuotes = !opt.StrictQuotes
+	cr.TrimLeadingSpace = !opt.KeepSpace
+	cr.FieldsPerRecord = -1 // kraći/duži redovi se čitaju, ne preskaču
+	cr.ReuseRecord = true   // NextRecord: bez alokacije slice-a po redu
+	return &Reader{cr: cr, reuse: true, onBad: opt.OnBadRow}
 }
 
 func (r *Reader) init() error {
@@ -43,13 +142,25 @@ func (r *Reader) init() error {
 	if err != nil {
 		return err
 	}
-	r.header = h
-	r.index = make(map[string]int, len(h))
-	for i, name := range h {
-		r.index[strings.TrimSpace(name)] = i
+	r.setHeader(h)
+	return nil
+}
+
+func (r *Reader) setHeader(h []string) {
+	r.header = append([]string(nil), h...)
+	if len(r.header) > 0 {
+		r.header[0] = strings.TrimPrefix(r.header[0], "\ufeff")
+	}
+	r.index = make(map[string]int, len(r.header))
+	r.lower = make(map[string]int, len(r.header))
+	for i, name := range r.header {
+		name = strings.TrimSpace(name)
+		r.index[name] = i
+		if _, dup := r.lower[strings.ToLower(name)]; !dup {
+			r.lower[strings.ToLower(name)] = i
+		}
 	}
 	r.inited = true
-	return nil
 }
 
 func (r *Reader) Header() ([]string, map[string]int, error) {
@@ -59,11 +170,33 @@ func (r *Reader) Header() ([]string, map[string]int, error) {
 	return r.header, r.index, nil
 }
 
-func (r *Reader) Next() (map[string]string, error) {
+// Stats: brojači do sada pročitanih redova.
+func (r *Reader) Stats() Stats { return Stats{Rows: r.rows, Bad: r.bad} }
+
+// next: sledeći ispravan red; loši redovi se preskaču i broje.
+func (r *Reader) next() ([]string, error) {
 	if err := r.init(); err != nil {
 		return nil, err
 	}
-	rec, err := r.cr.Read()
+	for {
+		rec, err := r.cr.Read()
+		if err == nil {
+			r.rows++
+			return rec, nil
+		}
+		var pe *csv.ParseError
+		if !errors.As(err, &pe) {
+			return nil, err
+		}
+		r.bad++
+		if r.onBad != nil {
+			r.onBad(err)
+		}
+	}
+}
+
+func (r *Reader) Next() (map[string]string, error) {
+	rec, err := r.next()
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +211,92 @@ func (r *Reader) Next() (map[string]string, error) {
 	return row, nil
 }
 
+// Read vraća sledeći red kao polja u Header() redosledu, bez mape
+// (indeksi iz Header()); slice je nov za svaki red, pa se sme zadržati
+// (npr. predati radniku).
+func (r *Reader) Read() ([]string, error) {
+	rec, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if r.reuse {
+		rec = append([]string(nil), rec...)
+	}
+	return rec, nil
+}
+
+// Col je indeks kolone razrešen jednom po imenu (Reader.Col).
+type Col int
+
+// NoCol: kolona ne postoji u header-u; Record.At vraća "".
+const NoCol Col = -1
+
+// Col razrešava ime kolone u indeks: prvo tačno ime, pa case-insensitive.
+func (r *Reader) Col(name string) Col {
+	if err := r.init(); err != nil {
+		return NoCol
+	}
+	name = strings.TrimSpace(name)
+	if i, ok := r.index[name]; ok {
+		return Col(i)
+	}
+	if i, ok := r.lower[strings.ToLower(name)]; ok {
+		return Col(i)
+	}
+	return NoCol
+}
+
+// ColAny: prva kolona header-a (redosledom header-a) čije ime je neki od
+// aliasa, case-insensitive. NoCol ako nijedan ne postoji.
+func (r *Reader) ColAny(aliases ...string) Col {
+	if err := r.init(); err != nil {
+		return NoCol
+	}
+	for i, h := range r.header {
+		h = strings.TrimSpace(h)
+		for _, a := range aliases {
+			if strings.EqualFold(h, strings.TrimSpace(a)) {
+				return Col(i)
+			}
+		}
+	}
+	return NoCol
+}
+
+// Record je jedan red vezan za header svog Reader-a. Polja su u slice-u
+// koji Reader reciklira: Record važi do sledećeg NextRecord (same string
+// vrednosti se smeju zadržati, slice Fields() ne).
+type Record struct {
+	fields []string
+	r      *Reader
+}
+
+// At: vrednost kolone c ("" za NoCol ili kraći red).
+func (rec Record) At(c Col) string {
+	if c >= 0 && int(c) < len(rec.fields) {
+		return rec.fields[c]
+	}
+	return ""
+}
+
+// Get: vrednost kolone po imenu (kao Col). U petlji je brže At sa Col
+// razrešenim van petlje.
+func (rec Record) Get(col string) string {
+	return rec.At(rec.r.Col(col))
+}
+
+// Fields: polja u Header() redosledu (reciklirani slice).
+func (rec Record) Fields() []string { return rec.fields }
+
+// NextRecord vraća sledeći red bez mape i bez kopiranja polja.
+func (r *Reader) NextRecord() (Record, error) {
+	rec, err := r.next()
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{fields: rec, r: r}, nil
+}
+
 func MustGet(row map[string]string, key string) string {
 	if v, ok := row[key]; ok {
 		return v
@@ -0,0 +1,92 @@
+package csvin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const benchRows = 1_000_000
+
+// benchCSV piše CSV od benchRows redova sa kolonama iz parser izlaza.
+func benchCSV(b *testing.B) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "bench.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "host_ip,time_zone,status_code,size,user_agent,method,referring_page,botName,verified,country")
+	for i := 0; i < benchRows; i++ {
+		fmt.Fprintf(w, "66.249.%d.%d,2025-09-01 10:%02d:%02d,200,%d,\"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)\",GET,https://example.com/p/%d,Googlebot,1,US\n",
+			i>>8&255, i&255, i/60%60, i%60, 1000+i%5000, i%1000)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkNext(b *testing.B) {
+	path := benchCSV(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := Open(path, Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		var n, rows int
+		for {
+			row, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			n += len(row["botName"])
+			rows++
+		}
+		_ = r.Close()
+		if rows != benchRows {
+			b.Fatalf("rows=%d, want %d", rows, benchRows)
+		}
+	}
+}
+
+func BenchmarkNextRecord(b *testing.B) {
+	path := benchCSV(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := Open(path, Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		bot := r.Col("botName")
+		var n, rows int
+		for {
+			rec, err := r.NextRecord()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			n += len(rec.At(bot))
+			rows++
+		}
+		_ = r.Close()
+		if rows != benchRows {
+			b.Fatalf("rows=%d, want %d", rows, benchRows)
+		}
+	}
+}
@@ -4,11 +4,22 @@ import (
 	"bufio"
 	"encoding/csv"
 	"io"
+
+	"parser/internal/iox"
+	"parser/internal/parquetio"
+	"parser/internal/schema"
 )
 
 type Writer struct {
 	w   *csv.Writer
 	buf *bufio.Writer
+
+	// Parquet (NewFor sa .parquet putanjom); pravi se u WriteHeader
+	pqOut io.Writer
+	pq    *parquetio.Writer
+
+	v       *schema.Validator // opciono (Validate)
+	rejects *Writer           // ModeReject: odbačeni redovi
 }
 
 func New(w io.Writer) *Writer {
@@ -19,15 +30,62 @@ func New(w io.Writer) *Writer {
 	}
 }
 
+// NewFor bira format po ekstenziji izlaza, kao iox.CreateAuto za gzip:
+// .parquet → Parquet (tipovi iz schema.Kind), inače CSV.
+func NewFor(w io.Writer, path string) *Writer {
+	if iox.IsParquet(path) {
+		return &Writer{pqOut: w}
+	}
+	return New(w)
+}
+
 func (cw *Writer) WriteHeader(header []string) error {
+	if cw.pqOut != nil {
+		pq, err := parquetio.NewWriter(cw.pqOut, header)
+		if err != nil {
+			return err
+		}
+		cw.pq = pq
+		return nil
+	}
 	return cw.w.Write(header)
 }
 
+// Validate uključuje proveru redova po schema.Kind; rejects je writer za
+// odbačene redove (ModeReject, može biti nil — red se tada samo preskače).
+func (cw *Writer) Validate(v *schema.Validator, rejects *Writer) {
+	cw.v = v
+	cw.rejects = rejects
+}
+
 func (cw *Writer) WriteRow(row []string) error {
+	if cw.v != nil {
+		keep, err := cw.v.Apply(row)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			if cw.rejects != nil {
+				return cw.rejects.WriteRow(row)
+			}
+			return nil
+		}
+	}
+	if cw.pq != nil {
+		return cw.pq.WriteRow(row)
+	}
 	return cw.w.Write(row)
 }
 
+// Flush: za Parquet upisuje footer (zatvara fajl logički), pa se zove jednom
+// na kraju faze, kao i do sada.
 func (cw *Writer) Flush() error {
+	if cw.pqOut != nil {
+		if cw.pq == nil {
+			return nil
+		}
+		return cw.pq.Close()
+	}
 	cw.w.Flush()
 	if err := cw.w.Error(); err != nil {
 		return err
@@ -0,0 +1,211 @@
+package dnscache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"parser/internal/verifier"
+)
+
+var (
+	bucket   = []byte("ip")
+	meta     = []byte("meta")
+	rulesKey = []byte("rules")
+)
+
+type Options struct {
+	PositiveTTL time.Duration // koliko dugo važi verified=1 presuda
+	NegativeTTL time.Duration // koliko dugo važi verified=0 presuda
+
+	// RulesHash: otisak bot pravila (botdetector.Fingerprint). Presude
+	// zavise od pravila (PTR sufiksi, imena), pa se keš sačuvan sa drugim
+	// otiskom briše pri Open. Prazno = bez provere.
+	RulesHash string
+}
+
+// Stats: brojači za log na kraju verify stage-a.
+type Stats struct {
+	Hits    int64
+	Misses  int64
+	Expired int64 // postojao unos, ali je TTL istekao (računa se i kao miss)
+	Corrupt int64 // unos koji nije validan JSON (loguje se, računa se i kao miss)
+	Stored  int64
+	// Invalidated: unosi obrisani pri Open jer su pravila promenjena
+	Invalidated int
+}
+
+// Cache: perzistentni IP→PTR/presuda keš u jednom bbolt fajlu.
+type Cache struct {
+	db    *bolt.DB
+	opt   Options
+	Stats Stats
+}
+
+type entry struct {
+	BotName  string `json:"bot"`
+	Verified bool   `json:"verified"`
+	Hostname string `json:"host,omitempty"`
+	Reason   string `json:"reason"`
+	Method   string `json:"method,omitempty"`
+	Checked  int64  `json:"checked"` // unix sekunde
+}
+
+// Open otvara (ili kreira) keš fajl. Ako je fajl zaključan od strane
+// drugog procesa, odustaje posle 5s umesto da visi.
+func Open(path string, opt Options) (*Cache, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("dnscache: open %s: %w", path, err)
+	}
+	c := &Cache{db: db, opt: opt}
+	if err := db.Update(c.init); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("dnscache: init: %w", err)
+	}
+	return c, nil
+}
+
+// init kreira bucket-e i briše presude sačuvane sa drugim otiskom pravila.
+func (c *Cache) init(tx *bolt.Tx) error {
+	m, err := tx.CreateBucketIfNotExists(meta)
+	if err != nil {
+		return err
+	}
+	b, err := tx.CreateBucketIfNotExists(bucket)
+	if err != nil {
+		return err
+	}
+	if c.opt.RulesHash == "" || string(m.Get(rulesKey)) == c.opt.RulesHash {
+		return nil
+	}
+	if n := b.Stats().KeyN; n > 0 {
+		if err := tx.DeleteBucket(bucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(bucket); err != nil {
+			return err
+		}
+		c.Stats.Invalidated = n
+	}
+	return m.Put(rulesKey, []byte(c.opt.RulesHash))
+}
+
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+func (c *Cache) ttl(e entry) time.Duration {
+	if e.Verified {
+		return c.opt.PositiveTTL
+	}
+	return c.opt.NegativeTTL
+}
+
+// Lookup deli IP adrese na one sa važećim unosom u kešu (hits) i one
+// koje treba proveriti preko DNS-a (misses).
+func (c *Cache) Lookup(ips []string, now time.Time) ([]verifier.Result, []string, error) {
+	hits := make([]verifier.Result, 0, len(ips))
+	misses := make([]string, 0, len(ips))
+
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, ip := range ips {
+			v := b.Get([]byte(ip))
+			if v == nil {
+				c.Stats.Misses++
+				misses = append(misses, ip)
+				continue
+			}
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				log.Printf("dns cache: corrupt entry for %s (%v), verifying again", ip, err)
+				c.Stats.Corrupt++
+				c.Stats.Misses++
+				misses = append(misses, ip)
+				continue
+			}
+			if now.Sub(time.Unix(e.Checked, 0)) > c.ttl(e) {
+				c.Stats.Expired++
+				c.Stats.Misses++
+				misses = append(misses, ip)
+				continue
+			}
+			c.Stats.Hits++
+			hits = append(hits, verifier.Result{
+				IP:       ip,
+				BotName:  e.BotName,
+				Verified: e.Verified,
+				Hostname: e.Hostname,
+				Reason:   e.Reason,
+				Method:   e.Method,
+			})
+		}
+		return nil
+	})
+	return hits, misses, err
+}
+
+// Store upisuje sveže rezultate u jednoj transakciji.
+// lookup_error presude se ne keširaju (prolazne greške), kao ni CIDR
+// presude (jeftine su i uvek se računaju iz aktuelnih lista).
+func (c *Cache) Store(results []verifier.Result, now time.Time) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, r := range results {
+			if r.Reason == verifier.ReasonLookupError || r.Method == verifier.MethodCIDR {
+				continue
+			}
+			v, err := json.Marshal(entry{
+				BotName:  r.BotName,
+				Verified: r.Verified,
+				Hostname: r.Hostname,
+				Reason:   r.Reason,
+				Method:   r.Method,
+				Checked:  now.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(r.IP), v); err != nil {
+				return err
+			}
+			c.Stats.Stored++
+		}
+		return nil
+	})
+}
+
+// Prune briše unose kojima je TTL istekao i neispravne (JSON) unose, uz
+// log; vraća broj obrisanih.
+func (c *Cache) Prune(now time.Time) (int, error) {
+	n := 0
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		var stale [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				log.Printf("dns cache: dropping corrupt entry for %s (%v)", k, err)
+				c.Stats.Corrupt++
+			} else if now.Sub(time.Unix(e.Checked, 0)) <= c.ttl(e) {
+				return nil
+			}
+			stale = append(stale, append([]byte(nil), k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(stale)
+		return nil
+	})
+	return n, err
+}
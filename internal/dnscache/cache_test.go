@@ -0,0 +1,212 @@
+package dnscache
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"parser/internal/verifier"
+)
+
+var (
+	t0  = time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	ttl = Options{PositiveTTL: 30 * 24 * time.Hour, NegativeTTL: 24 * time.Hour}
+
+	good = verifier.Result{IP: "66.249.66.1", BotName: "Googlebot", Verified: true,
+		Hostname: "crawl-66-249-66-1.googlebot.com", Reason: verifier.ReasonConfirmed, Method: verifier.MethodDNS}
+	bad = verifier.Result{IP: "1.2.3.4", BotName: "evil.net",
+		Reason: verifier.ReasonUnknownPTR, Method: verifier.MethodDNS}
+)
+
+func open(t *testing.T, path string, opt Options) *Cache {
+	t.Helper()
+	c, err := Open(path, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func hitIPs(hits []verifier.Result) []string {
+	ips := make([]string, 0, len(hits))
+	for _, h := range hits {
+		ips = append(ips, h.IP)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// Pozitivna presuda važi PositiveTTL, negativna NegativeTTL.
+func TestLookupTTL(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "dns.db"), ttl)
+	if err := c.Store([]verifier.Result{good, bad}, t0); err != nil {
+		t.Fatal(err)
+	}
+	ips := []string{good.IP, bad.IP, "8.8.8.8"}
+	cases := []struct {
+		name  string
+		after time.Duration
+		hits  []string
+	}{
+		{"odmah", 0, []string{bad.IP, good.IP}},
+		{"na granici negativnog TTL-a", ttl.NegativeTTL, []string{bad.IP, good.IP}},
+		{"posle negativnog TTL-a", ttl.NegativeTTL + time.Second, []string{good.IP}},
+		{"posle pozitivnog TTL-a", ttl.PositiveTTL + time.Second, []string{}},
+	}
+	for _, tc := range cases {
+		hits, misses, err := c.Lookup(ips, t0.Add(tc.after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hitIPs(hits); !reflect.DeepEqual(got, tc.hits) {
+			t.Errorf("%s: hits = %v, want %v", tc.name, got, tc.hits)
+		}
+		if len(hits)+len(misses) != len(ips) {
+			t.Errorf("%s: hits+misses = %d, want %d", tc.name, len(hits)+len(misses), len(ips))
+		}
+	}
+	hits, _, _ := c.Lookup([]string{good.IP}, t0)
+	if len(hits) != 1 || hits[0] != good {
+		t.Errorf("hit = %+v, want %+v", hits, good)
+	}
+	// 4 Lookup-a gore: 8.8.8.8 nikad nije u kešu, bad ističe u 2, good u 1
+	if c.Stats.Expired != 3 || c.Stats.Misses != 7 {
+		t.Errorf("Stats = %+v, want Expired=3 Misses=7", c.Stats)
+	}
+}
+
+// lookup_error i CIDR presude se ne upisuju.
+func TestStoreSkips(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "dns.db"), ttl)
+	results := []verifier.Result{
+		good,
+		{IP: "9.9.9.9", Reason: verifier.ReasonLookupError, Method: verifier.MethodDNS},
+		{IP: "66.249.66.2", BotName: "Googlebot", Verified: true, Reason: verifier.ReasonCIDRMatch, Method: verifier.MethodCIDR},
+	}
+	if err := c.Store(results, t0); err != nil {
+		t.Fatal(err)
+	}
+	if c.Stats.Stored != 1 {
+		t.Errorf("Stored = %d, want 1", c.Stats.Stored)
+	}
+	hits, misses, err := c.Lookup([]string{good.IP, "9.9.9.9", "66.249.66.2"}, t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hitIPs(hits); !reflect.DeepEqual(got, []string{good.IP}) {
+		t.Errorf("hits = %v", got)
+	}
+	if want := []string{"9.9.9.9", "66.249.66.2"}; !reflect.DeepEqual(misses, want) {
+		t.Errorf("misses = %v, want %v", misses, want)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "dns.db"), ttl)
+	if err := c.Store([]verifier.Result{good, bad}, t0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Prune(t0.Add(time.Hour)); err != nil || n != 0 {
+		t.Fatalf("Prune pre isteka = (%d, %v), want 0", n, err)
+	}
+	if n, err := c.Prune(t0.Add(ttl.NegativeTTL + time.Second)); err != nil || n != 1 {
+		t.Fatalf("Prune = (%d, %v), want 1", n, err)
+	}
+	// obrisan unos je promašaj i kad bi TTL bio duži
+	c.opt.NegativeTTL = ttl.PositiveTTL
+	hits, _, _ := c.Lookup([]string{good.IP, bad.IP}, t0)
+	if got := hitIPs(hits); !reflect.DeepEqual(got, []string{good.IP}) {
+		t.Errorf("posle Prune hits = %v, want [%s]", got, good.IP)
+	}
+}
+
+// Promena otiska pravila briše ip bucket pri Open; isti otisak ga čuva.
+func TestRulesHashInvalidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns.db")
+	reopen := func(hash string) *Cache {
+		opt := ttl
+		opt.RulesHash = hash
+		c, err := Open(path, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := reopen("a")
+	if err := c.Store([]verifier.Result{good, bad}, t0); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	steps := []struct {
+		hash        string
+		invalidated int
+		hits        int
+	}{
+		{"a", 0, 2},
+		{"", 0, 2}, // bez otiska: bez provere
+		{"b", 2, 0},
+		{"a", 0, 0}, // bucket je već prazan
+	}
+	for _, st := range steps {
+		c := reopen(st.hash)
+		hits, _, err := c.Lookup([]string{good.IP, bad.IP}, t0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Stats.Invalidated != st.invalidated || len(hits) != st.hits {
+			t.Errorf("hash %q: Invalidated=%d hits=%d, want %d, %d", st.hash, c.Stats.Invalidated, len(hits), st.invalidated, st.hits)
+		}
+		c.Close()
+	}
+}
+
+// Neispravan unos se loguje i računa kao Corrupt (ne Expired); Prune ga briše.
+func TestCorruptEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns.db")
+	c := open(t, path, ttl)
+	if err := c.Store([]verifier.Result{good}, t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(bad.IP), []byte("{nije json"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	hits, misses, err := c.Lookup([]string{good.IP, bad.IP}, t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || !reflect.DeepEqual(misses, []string{bad.IP}) {
+		t.Errorf("hits=%v misses=%v", hits, misses)
+	}
+	if c.Stats.Corrupt != 1 || c.Stats.Expired != 0 {
+		t.Errorf("Stats = %+v, want Corrupt=1 Expired=0", c.Stats)
+	}
+	if !strings.Contains(buf.String(), "corrupt entry for "+bad.IP) {
+		t.Errorf("nema loga za neispravan unos: %q", buf.String())
+	}
+
+	buf.Reset()
+	if n, err := c.Prune(t0); err != nil || n != 1 {
+		t.Fatalf("Prune = (%d, %v), want 1", n, err)
+	}
+	if !strings.Contains(buf.String(), "dropping corrupt entry for "+bad.IP) {
+		t.Errorf("nema loga za Prune: %q", buf.String())
+	}
+}
@@ -0,0 +1,140 @@
+// Package edgestats su pravila za edge tabele (country, colo, cache
+// status, TTFB) koje pune i geninsert (gen) i ingest, pa isti CSV daje
+// iste redove bez obzira ko ga upisuje:
+//
+//   - ključevi: country/colo → UPPER, cache_status → lower, bot → BotKey;
+//     prazne vrednosti se ne broje
+//   - valueProp: udeo u redovima sa vrednošću (zbir brojača), 2 decimale
+//   - TTFB: tačni p50/p95 iz histograma po ms (Timing)
+//   - upis: DELETE za (project_id, month, year) pa INSERT, u jednoj
+//     transakciji; tabela bez ijednog ključa se ne dira
+//
+// DDL je u internal/ingest/schema/edge_tables.sql (schema.EdgeDDL); tabele
+// su opcione, pa pozivaoci proveravaju da postoje (Tables).
+package edgestats
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"parser/internal/botdetector"
+)
+
+// Counts: tabela oblika (Col, value, valueProp, month, year, project_id).
+type Counts struct {
+	Table string
+	Col   string // kolona ključa u tabeli
+	// Key: vrednost CSV kolone → ključ; ok=false se ne broji.
+	Key func(v string) (string, bool)
+}
+
+var (
+	Country     = Counts{Table: "ln_genBotsMainStatsByCountry", Col: "country", Key: upperKey}
+	Colo        = Counts{Table: "ln_genBotsMainStatsByColo", Col: "colo", Key: upperKey}
+	CacheStatus = Counts{Table: "ln_genBotsMainStatsByCacheStatus", Col: "cache_status", Key: lowerKey}
+)
+
+// TTFBTable: (botName, value, avgTtfb, p50Ttfb, p95Ttfb, avgOriginTime, month, year, project_id)
+const TTFBTable = "ln_genBotsMainStatsByTTFB"
+
+// Tables: sve edge tabele (za schema.HasTables).
+var Tables = []string{Country.Table, Colo.Table, CacheStatus.Table, TTFBTable}
+
+func upperKey(v string) (string, bool) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	return v, v != ""
+}
+
+func lowerKey(v string) (string, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	return v, v != ""
+}
+
+// Unverified: botName koji verifier upisuje kad IP nema PTR zapis.
+const Unverified = "unable to verify bot"
+
+// BotKey svodi botName na jedan ključ po botu (isti kao ln_genBotsMainStats):
+//   - kod "Label|PTR" gleda se poslednji deo (presuda verifier-a)
+//   - ime pravila (potvrđena presuda, UA labela) ostaje ime pravila
+//   - PTR (nepotvrđena presuda) → ime pravila ako ga pokriva ptr_suffixes
+//     nekog pravila ("googlebot.com" → "Googlebot"), inače bazni domen
+//
+// Prazan botName i Unverified se ne broje (ok=false).
+func BotKey(raw string) (string, bool) {
+	s := strings.TrimSpace(raw)
+	if strings.Contains(s, "|") {
+		parts := strings.Split(s, "|")
+		s = strings.TrimSpace(parts[len(parts)-1]) // očekujemo presudu na kraju
+	}
+	if s == "" || s == Unverified {
+		return "", false
+	}
+	if bot, ok := botdetector.Lookup(s); ok {
+		return bot.Name, true
+	}
+	if !strings.Contains(s, ".") {
+		return s, true // labela bez PTR-a
+	}
+	h := stripNumericPrefix(s)
+	if bot, ok := botdetector.MatchPTR(h); ok {
+		return bot.Name, true
+	}
+	return baseDomain(h), true
+}
+
+// stripNumericPrefix: odbaci vodeće labele koje sadrže cifru
+// npr. "66-249-66-1.googlebot.com" -> "googlebot.com"
+func stripNumericPrefix(host string) string {
+	h := strings.ToLower(strings.TrimSpace(strings.TrimSuffix(host, ".")))
+	labels := strings.Split(h, ".")
+	i := 0
+	for i < len(labels) && strings.IndexFunc(labels[i], unicode.IsDigit) >= 0 {
+		i++
+	}
+	if i >= len(labels) {
+		return h
+	}
+	return strings.Join(labels[i:], ".")
+}
+
+// baseDomain vraća eTLD+1 za tipične slučajeve (heuristika, uključuje .co.uk varijantu)
+func baseDomain(h string) string {
+	parts := strings.Split(h, ".")
+	if len(parts) < 2 {
+		return h
+	}
+	last := parts[len(parts)-1]
+	second := parts[len(parts)-2]
+	// gruba podrška za UK višeslojne TLD-ove
+	if last == "uk" && (second == "co" || second == "ac" || second == "gov" || second == "ltd" || second == "plc" || second == "org") && len(parts) >= 3 {
+		return parts[len(parts)-3] + "." + second + "." + last
+	}
+	return second + "." + last
+}
+
+// MS parsira ms vrednost (ttfb_ms, origin_time_ms): ceo broj >= 0.
+func MS(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Prop: n kao procenat od total, 2 decimale (0 za total=0).
+func Prop(n, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return round2(float64(n) * 100 / float64(total))
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,173 @@
+package edgestats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	cases := []struct {
+		c      Counts
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{Country, " us ", "US", true},
+		{Country, "", "", false},
+		{Country, "  ", "", false},
+		{Colo, "fra", "FRA", true},
+		{CacheStatus, "HIT", "hit", true},
+		{CacheStatus, " Dynamic", "dynamic", true},
+		{CacheStatus, "", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := tc.c.Key(tc.in)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%s.Key(%q) = (%q, %v), want (%q, %v)", tc.c.Table, tc.in, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestBotKey(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"Googlebot", "Googlebot", true},
+		{"googlebot", "Googlebot", true},                                 // ime pravila, case-insensitive
+		{"Googlebot|crawl-66-249-66-1.googlebot.com", "Googlebot", true}, // presuda na kraju
+		{"crawl-66-249-66-1.googlebot.com", "Googlebot", true},           // PTR pod ptr_suffixes
+		{"msnbot-40-77-167-1.search.msn.com.", "Bingbot", true},
+		{"crawl.googlebot.com.evil.net", "evil.net", true}, // nepoznat PTR → bazni domen
+		{"host-1.example.co.uk", "example.co.uk", true},
+		{"SomeLabel", "SomeLabel", true}, // labela bez PTR-a
+		{"Googlebot|" + Unverified, "", false},
+		{Unverified, "", false},
+		{"", "", false},
+		{" | ", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := BotKey(tc.in)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("BotKey(%q) = (%q, %v), want (%q, %v)", tc.in, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestMS(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   int64
+		wantOK bool
+	}{
+		{"12", 12, true},
+		{" 0 ", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"1.5", 0, false},
+		{"abc", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := MS(tc.in)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("MS(%q) = (%d, %v), want (%d, %v)", tc.in, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestProp(t *testing.T) {
+	cases := []struct {
+		n, total int64
+		want     float64
+	}{
+		{1, 3, 33.33},
+		{2, 3, 66.67},
+		{3, 3, 100},
+		{1, 8, 12.5},
+		{0, 5, 0},
+		{5, 0, 0},
+		{5, -1, 0},
+	}
+	for _, tc := range cases {
+		if got := Prop(tc.n, tc.total); got != tc.want {
+			t.Errorf("Prop(%d, %d) = %v, want %v", tc.n, tc.total, got, tc.want)
+		}
+	}
+}
+
+func TestTimingPercentile(t *testing.T) {
+	cases := []struct {
+		name     string
+		ttfb     []int64
+		p50, p95 int64
+	}{
+		{"prazno", nil, 0, 0},
+		{"jedna vrednost", []int64{42}, 42, 42},
+		{"1..100", seq(1, 100), 50, 95},
+		{"ponavljanja", []int64{10, 10, 10, 20}, 10, 10},
+		{"rep", []int64{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 900}, 5, 5},
+		{"neuređen ulaz", []int64{300, 100, 200}, 200, 200},
+	}
+	for _, tc := range cases {
+		ts := Timings{}
+		ts.get("Googlebot")
+		for _, v := range tc.ttfb {
+			ts.Add("Googlebot", v, 0, false)
+		}
+		tm := ts["Googlebot"]
+		if got := tm.Percentile(0.50); got != tc.p50 {
+			t.Errorf("%s: p50 = %d, want %d", tc.name, got, tc.p50)
+		}
+		if got := tm.Percentile(0.95); got != tc.p95 {
+			t.Errorf("%s: p95 = %d, want %d", tc.name, got, tc.p95)
+		}
+	}
+}
+
+func TestTimingsAvgAndMerge(t *testing.T) {
+	all := Timings{}
+	a, b := Timings{}, Timings{}
+	rows := []struct {
+		bot          string
+		ttfb, origin int64
+		hasOrigin    bool
+	}{
+		{"Googlebot", 10, 100, true},
+		{"Googlebot", 20, 0, false},
+		{"Googlebot", 30, 300, true},
+		{"Bingbot", 7, 0, false},
+	}
+	for i, r := range rows {
+		all.Add(r.bot, r.ttfb, r.origin, r.hasOrigin)
+		part := a
+		if i%2 == 1 {
+			part = b
+		}
+		part.Add(r.bot, r.ttfb, r.origin, r.hasOrigin)
+	}
+	a.Merge(b)
+	if !reflect.DeepEqual(a, all) {
+		t.Fatalf("Merge: %+v, want %+v", a, all)
+	}
+
+	g := all["Googlebot"]
+	if g.N != 3 || g.Avg() != 20 || g.OriginN != 2 || g.AvgOrigin() != 200 {
+		t.Errorf("Googlebot: N=%d avg=%v originN=%d avgOrigin=%v, want 3 20 2 200", g.N, g.Avg(), g.OriginN, g.AvgOrigin())
+	}
+	bg := all["Bingbot"]
+	if bg.AvgOrigin() != 0 || bg.Avg() != 7 {
+		t.Errorf("Bingbot: avg=%v avgOrigin=%v, want 7 0", bg.Avg(), bg.AvgOrigin())
+	}
+	if (&Timing{}).Avg() != 0 {
+		t.Error("Avg bez redova mora biti 0")
+	}
+}
+
+func seq(from, to int64) []int64 {
+	var out []int64
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package edgestats
+
+import "sort"
+
+// Timing: histogram TTFB vrednosti po ms (tačni percentili) + suma origin
+// vremena, za jednog bota.
+type Timing struct {
+	Hist      map[int64]int64 // ms -> broj pogodaka
+	N         int64
+	Sum       int64
+	OriginN   int64
+	OriginSum int64
+}
+
+// Timings: BotKey → Timing.
+type Timings map[string]*Timing
+
+// Add dodaje jedan red; origin se računa samo ako hasOrigin.
+func (ts Timings) Add(bot string, ttfb, origin int64, hasOrigin bool) {
+	t := ts.get(bot)
+	t.Hist[ttfb]++
+	t.N++
+	t.Sum += ttfb
+	if hasOrigin {
+		t.OriginN++
+		t.OriginSum += origin
+	}
+}
+
+// Merge dodaje brojače iz o (npr. drugog radnika).
+func (ts Timings) Merge(o Timings) {
+	for bot, ot := range o {
+		t := ts.get(bot)
+		for ms, n := range ot.Hist {
+			t.Hist[ms] += n
+		}
+		t.N += ot.N
+		t.Sum += ot.Sum
+		t.OriginN += ot.OriginN
+		t.OriginSum += ot.OriginSum
+	}
+}
+
+func (ts Timings) get(bot string) *Timing {
+	t := ts[bot]
+	if t == nil {
+		t = &Timing{Hist: make(map[int64]int64)}
+		ts[bot] = t
+	}
+	return t
+}
+
+func (t *Timing) Avg() float64 {
+	if t.N == 0 {
+		return 0
+	}
+	return float64(t.Sum) / float64(t.N)
+}
+
+func (t *Timing) AvgOrigin() float64 {
+	if t.OriginN == 0 {
+		return 0
+	}
+	return float64(t.OriginSum) / float64(t.OriginN)
+}
+
+// Percentile: q u [0,1], nearest-rank.
+func (t *Timing) Percentile(q float64) int64 {
+	if t.N == 0 {
+		return 0
+	}
+	keys := make([]int64, 0, len(t.Hist))
+	for k := range t.Hist {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	rank := int64(q*float64(t.N-1)) + 1
+	var seen int64
+	for _, k := range keys {
+		seen += t.Hist[k]
+		if seen >= rank {
+			return k
+		}
+	}
+	return keys[len(keys)-1]
+}
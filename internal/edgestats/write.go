@@ -0,0 +1,109 @@
+package edgestats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// Period: (project_id, month, year) ključ meseca u svim tabelama.
+type Period struct {
+	ProjectID int64
+	Month     int
+	Year      int
+}
+
+// Write upisuje cnt u c.Table: briše mesec p pa upisuje sve ključeve
+// (Count DESC, ključ ASC). Prazan cnt ne dira tabelu.
+func (c Counts) Write(ctx context.Context, db *sql.DB, cnt map[string]int64, p Period) error {
+	if len(cnt) == 0 {
+		return nil
+	}
+	var total int64
+	keys := make([]string, 0, len(cnt))
+	for k, n := range cnt {
+		keys = append(keys, k)
+		total += n
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if cnt[keys[i]] == cnt[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return cnt[keys[i]] > cnt[keys[j]]
+	})
+
+	q := fmt.Sprintf("INSERT INTO %s (%s, value, valueProp, month, year, project_id) VALUES (?, ?, ?, ?, ?, ?)", c.Table, c.Col)
+	return replaceMonth(ctx, db, c.Table, q, p, len(keys), func(i int) []any {
+		k := keys[i]
+		return []any{k, cnt[k], Prop(cnt[k], total), p.Month, p.Year, p.ProjectID}
+	})
+}
+
+// WriteTTFB upisuje ts u TTFBTable (botName ASC), kao Counts.Write.
+func WriteTTFB(ctx context.Context, db *sql.DB, ts Timings, p Period) error {
+	if len(ts) == 0 {
+		return nil
+	}
+	bots := make([]string, 0, len(ts))
+	for b := range ts {
+		bots = append(bots, b)
+	}
+	sort.Strings(bots)
+
+	q := "INSERT INTO " + TTFBTable + " (botName, value, avgTtfb, p50Ttfb, p95Ttfb, avgOriginTime, month, year, project_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	return replaceMonth(ctx, db, TTFBTable, q, p, len(bots), func(i int) []any {
+		t := ts[bots[i]]
+		return []any{
+			truncateRunes(bots[i], 255), t.N,
+			round2(t.Avg()), t.Percentile(0.50), t.Percentile(0.95), round2(t.AvgOrigin()),
+			p.Month, p.Year, p.ProjectID,
+		}
+	})
+}
+
+// replaceMonth: DELETE meseca p + n INSERT-a (args(i)) u jednoj transakciji.
+func replaceMonth(ctx context.Context, db *sql.DB, table, insert string, p Period, n int, args func(i int) []any) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := tx.Rollback(); rerr != nil && rerr != sql.ErrTxDone {
+			log.Printf("[WARN] tx.Rollback failed: %v", rerr)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM "+table+" WHERE project_id=? AND month=? AND year=?",
+		p.ProjectID, p.Month, p.Year,
+	); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, insert)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if serr := stmt.Close(); serr != nil {
+			log.Printf("[WARN] stmt.Close failed: %v", serr)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		if _, err := stmt.ExecContext(ctx, args(i)...); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// truncateRunes skraćuje string na najviše max runa (karaktera).
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
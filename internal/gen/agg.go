@@ -0,0 +1,293 @@
+package gen
+
+import (
+	"context"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"parser/internal/csvin"
+)
+
+// ==============================
+// Agregacija u jednom prolazu
+// ==============================
+//
+// Aggregate čita CSV jednom i puni brojače svih traženih tabela (jedna
+// dimenzija = brojači za jednu gen tabelu, Table.spec). Table.Insert uzima
+// brojače iz Params.Agg; ako tabela tamo ne postoji, uradi prolaz samo za nju.
+//
+// Sa workers > 1 čitač puni batch-eve vrednosti potrebnih kolona, a svaki
+// radnik ima svoje brojače koji se na kraju spajaju (brojanje je komutativno,
+// pa je rezultat isti kao sa jednim radnikom).
+
+// dimState: brojači jedne dimenzije. add dobija vrednosti kolona dimenzije
+// (redosled iz dimSpec.cols); merge spaja brojače drugog radnika.
+type dimState interface {
+	add(vals []string)
+	merge(other dimState)
+}
+
+type dimSpec struct {
+	cols     []string
+	newState func() dimState
+}
+
+// --- ključevi brojača (value_counts) ---
+
+// keyFunc: trimovana vrednost kolone → ključ; ok=false preskače red.
+type keyFunc func(v string) (string, bool)
+
+// counter: value_counts jedne kolone.
+type counter struct {
+	key    keyFunc
+	counts map[string]int64
+	total  int64
+}
+
+func newCounter(key keyFunc) func() dimState {
+	return func() dimState { return &counter{key: key, counts: make(map[string]int64)} }
+}
+
+func (c *counter) add(vals []string) {
+	if k, ok := c.key(norm(vals[0])); ok {
+		inc(c.counts, k)
+		c.total++
+	}
+}
+
+func (c *counter) merge(other dimState) {
+	o := other.(*counter)
+	for k, n := range o.counts {
+		c.counts[k] += n
+	}
+	c.total += o.total
+}
+
+type kv struct {
+	Name  string
+	Count int64
+}
+
+// sorted: value_counts poredak (Count DESC, Name ASC) — stabilan između env-ova.
+func (c *counter) sorted() []kv {
+	pairs := make([]kv, 0, len(c.counts))
+	for name, n := range c.counts {
+		pairs = append(pairs, kv{name, n})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Count == pairs[j].Count {
+			return pairs[i].Name < pairs[j].Name
+		}
+		return pairs[i].Count > pairs[j].Count
+	})
+	return pairs
+}
+
+// verCounter: verified / unverified sume.
+type verCounter struct {
+	ver, unv int64
+}
+
+func (v *verCounter) add(vals []string) {
+	l := strings.ToLower(norm(vals[0]))
+	isTrue := l == "1" || l == "true" || l == "yes" || l == "y"
+	if !isTrue {
+		if n, err := strconv.Atoi(l); err == nil {
+			isTrue = (n != 0)
+		}
+	}
+	if isTrue {
+		v.ver++
+	} else {
+		v.unv++
+	}
+}
+
+func (v *verCounter) merge(other dimState) {
+	o := other.(*verCounter)
+	v.ver += o.ver
+	v.unv += o.unv
+}
+
+// --- Aggregate ---
+
+// Result: brojači svih tabela iz jednog prolaza (po Table.Name).
+type Result struct {
+	Rows, Bad int64
+	states    map[string]dimState
+	cols      map[string]bool // lowercase imena traženih kolona koje ulaz ima
+}
+
+// Has: da li je tabela agregirana.
+func (r *Result) Has(table string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.states[table]
+	return ok
+}
+
+// hasCol: da li je ulaz imao kolonu (case-insensitive).
+func (r *Result) hasCol(name string) bool {
+	return r != nil && r.cols[strings.ToLower(name)]
+}
+
+// aggBatchSize: redova po batch-u za radnike.
+const aggBatchSize = 1024
+
+// Aggregate čita path (CSV/gzip/zstd/Parquet, "-" = stdin) jednom i puni
+// brojače za tabs. workers > 1 deli redove na radnike.
+func Aggregate(ctx context.Context, path string, tabs []Table, workers int) (*Result, error) {
+	// raspored kolona: vrednosti reda su spojene kolone svih dimenzija
+	// (ista kolona može se ponoviti, npr. referring_page za refpage i sitemap)
+	var (
+		cols []string
+		offs = make([]int, len(tabs))
+	)
+	for i, t := range tabs {
+		offs[i] = len(cols)
+		cols = append(cols, t.spec.cols...)
+	}
+	width := len(cols)
+
+	r, err := openCSV(path, cols...)
+	if err != nil {
+		return nil, err
+	}
+	defer closeCSV(r)
+
+	// kolone koje ulaz nema: upozorenje jednom, dimenzija vidi prazne vrednosti
+	idx := make([]csvin.Col, width)
+	warned := make(map[string]bool)
+	have := make(map[string]bool, width)
+	for i, c := range cols {
+		if idx[i] = r.Col(c); idx[i] != csvin.NoCol {
+			have[strings.ToLower(c)] = true
+		} else if !warned[c] {
+			warned[c] = true
+			column(r, c)
+		}
+	}
+
+	newStates := func() []dimState {
+		st := make([]dimState, len(tabs))
+		for i, t := range tabs {
+			st[i] = t.spec.newState()
+		}
+		return st
+	}
+	addRow := func(st []dimState, vals []string) {
+		for i, s := range st {
+			s.add(vals[offs[i] : offs[i]+len(tabs[i].spec.cols)])
+		}
+	}
+
+	start := time.Now()
+	var states []dimState
+	if workers <= 1 {
+		states = newStates()
+		vals := make([]string, width)
+		for n := 1; ; n++ {
+			rec, err := r.NextRecord()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			for i, c := range idx {
+				vals[i] = rec.At(c)
+			}
+			addRow(states, vals)
+			if n%(1<<16) == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		if states, err = aggregateParallel(ctx, r, idx, workers, newStates, addRow); err != nil {
+			return nil, err
+		}
+	}
+
+	st := r.Stats()
+	res := &Result{Rows: st.Rows, Bad: st.Bad, states: make(map[string]dimState, len(tabs)), cols: have}
+	for i, t := range tabs {
+		res.states[t.Name] = states[i]
+	}
+	log.Printf("[INFO] aggregate: rows=%d bad=%d tables=%d workers=%d time=%s", res.Rows, res.Bad, len(tabs), max(workers, 1), time.Since(start))
+	return res, nil
+}
+
+// aggBatch: vrednosti aggBatchSize redova, width po redu.
+type aggBatch struct {
+	vals []string
+	n    int
+}
+
+func aggregateParallel(ctx context.Context, r *csvin.Reader, idx []csvin.Col, workers int,
+	newStates func() []dimState, addRow func([]dimState, []string)) ([]dimState, error) {
+	width := len(idx)
+	free := make(chan *aggBatch, 2*workers)
+	for i := 0; i < cap(free); i++ {
+		free <- &aggBatch{vals: make([]string, aggBatchSize*width)}
+	}
+	todo := make(chan *aggBatch, workers)
+
+	all := make([][]dimState, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		all[w] = newStates()
+		go func(st []dimState) {
+			defer wg.Done()
+			for b := range todo {
+				for j := 0; j < b.n; j++ {
+					addRow(st, b.vals[j*width:(j+1)*width])
+				}
+				clear(b.vals[:b.n*width]) // ne drži stringove ulaza do sledećeg punjenja
+				free <- b
+			}
+		}(all[w])
+	}
+
+	var rerr error
+	for rerr == nil {
+		b := <-free
+		b.n = 0
+		for b.n < aggBatchSize {
+			rec, err := r.NextRecord()
+			if err != nil {
+				rerr = err
+				break
+			}
+			row := b.vals[b.n*width : (b.n+1)*width]
+			for i, c := range idx {
+				row[i] = rec.At(c)
+			}
+			b.n++
+		}
+		if rerr == nil {
+			rerr = ctx.Err()
+		}
+		todo <- b
+	}
+	close(todo)
+	wg.Wait()
+	if rerr != io.EOF {
+		return nil, rerr
+	}
+
+	for _, st := range all[1:] {
+		for i, s := range st {
+			all[0][i].merge(s)
+		}
+	}
+	return all[0], nil
+}
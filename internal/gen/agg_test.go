@@ -0,0 +1,143 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const aggHeader = "host_ip,botName,verified,source,method,referring_page,target,protocol,country,edge_colo,cache_status,ttfb_ms,origin_time_ms"
+
+// aggRows: n redova sa ponavljanjem vrednosti, praznim poljima i kraćim
+// redovima (svaki 97. nema edge kolone).
+func aggRows(n int) []string {
+	bots := []string{"Googlebot", "66-249-66-1.googlebot.com", "GPTBot|openai.com", "unable to verify bot", "", "Bingbot"}
+	verified := []string{"1", "0", "true", "", "2"}
+	pages := []string{"https://example.com/", "https://example.com/sitemap.xml", "https://example.com/p/1", ""}
+	countries := []string{"us", "DE", " rs ", ""}
+	caches := []string{"HIT", "miss", "", "dynamic"}
+
+	rows := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		f := []string{
+			fmt.Sprintf("10.0.%d.%d", i/256%256, i%256),
+			bots[i%len(bots)],
+			verified[i%len(verified)],
+			[]string{"desktop", "mobile", ""}[i%3],
+			[]string{"GET", "HEAD", "POST"}[i%3],
+			pages[i%len(pages)],
+			[]string{"Page", "Image", "Stylesheet", ""}[i%4],
+			[]string{"HTTP/1.1", "HTTP/2", "HTTP/3"}[i%3],
+			countries[i%len(countries)],
+			[]string{"FRA", "ams", "BEG"}[i%3],
+			caches[i%len(caches)],
+			[]string{fmt.Sprint(i % 700), "", "-5", "x"}[i%4],
+			[]string{fmt.Sprint(i % 300), ""}[i%2],
+		}
+		if i%97 == 0 {
+			f = f[:8]
+		}
+		rows = append(rows, strings.Join(f, ","))
+	}
+	return rows
+}
+
+// snapshot: brojači dimenzije bez keyFunc-a (reflect.DeepEqual ne poredi funkcije).
+func snapshot(t *testing.T, st dimState) any {
+	t.Helper()
+	switch s := st.(type) {
+	case *counter:
+		return struct {
+			Counts map[string]int64
+			Total  int64
+		}{s.counts, s.total}
+	case *verCounter:
+		return *s
+	case timings:
+		return s
+	}
+	t.Fatalf("nepoznat dimState %T", st)
+	return nil
+}
+
+func TestAggregateWorkersInvariant(t *testing.T) {
+	cases := []struct {
+		name string
+		rows int
+	}{
+		{"samo header", 0},
+		{"manje od batch-a", 10},
+		{"tačno batch", aggBatchSize},
+		{"više batch-eva sa ostatkom", 5*aggBatchSize + 123},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "in.csv")
+			data := strings.Join(append([]string{aggHeader}, aggRows(tc.rows)...), "\n") + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			tabs := Tables()
+			want, err := Aggregate(ctx, path, tabs, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want.Rows != int64(tc.rows) || want.Bad != 0 {
+				t.Fatalf("workers=1: rows=%d bad=%d, want rows=%d bad=0", want.Rows, want.Bad, tc.rows)
+			}
+
+			for _, workers := range []int{2, 3, 8} {
+				got, err := Aggregate(ctx, path, tabs, workers)
+				if err != nil {
+					t.Fatalf("workers=%d: %v", workers, err)
+				}
+				if got.Rows != want.Rows || got.Bad != want.Bad {
+					t.Errorf("workers=%d: rows=%d bad=%d, want rows=%d bad=%d", workers, got.Rows, got.Bad, want.Rows, want.Bad)
+				}
+				for _, tab := range tabs {
+					w, g := snapshot(t, want.states[tab.Name]), snapshot(t, got.states[tab.Name])
+					if !reflect.DeepEqual(w, g) {
+						t.Errorf("workers=%d %s:\n got %+v\nwant %+v", workers, tab.Name, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Sa podacima brojači ne smeju biti prazni, inače bi poređenje gore bilo
+// trivijalno tačno.
+func TestAggregateCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.csv")
+	data := strings.Join(append([]string{aggHeader}, aggRows(3000)...), "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Aggregate(context.Background(), path, Tables(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tab := range Tables() {
+		empty := false
+		switch s := res.states[tab.Name].(type) {
+		case *counter:
+			empty = s.total == 0
+		case *verCounter:
+			empty = s.ver+s.unv == 0
+		case timings:
+			empty = len(s) == 0
+		}
+		if empty {
+			t.Errorf("%s: prazni brojači", tab.Name)
+		}
+	}
+	if v := res.states["ln_genBotsMainStatsByVerification"].(*verCounter); v.ver+v.unv != 3000 {
+		t.Errorf("verification: ver+unv=%d, want 3000", v.ver+v.unv)
+	}
+}
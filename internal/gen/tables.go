@@ -0,0 +1,228 @@
+package gen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"parser/internal/edgestats"
+)
+
+// ==============================
+// Registar gen tabela
+// ==============================
+//
+// Svaka tabela koju geninsert puni je jedan unos u tables: ime, flag
+// (--<Flag>), šta se agregira (spec) i writer koji upisuje brojače.
+// Tabele oblika (<kolona>, value, valueProp, month, year, project_id) se
+// opisuju deklarativno sa StatsTable — nova ln_genBotsMainStatsByX tabela
+// je jedan unos ovde.
+
+// Table: jedna gen tabela.
+type Table struct {
+	Name string // ln_genBotsMainStats...
+	Flag string // geninsert --<Flag>
+
+	// Optional: edge tabela (DDL: schema.EdgeDDL, primenjuje se ručno), pa je
+	// geninsert puni samo ako postoji u bazi; Insert je preskače ako ulaz
+	// nema kolonu need.
+	Optional bool
+	need     string
+
+	spec  dimSpec
+	write func(ctx context.Context, db *sql.DB, p Params, st dimState) error
+}
+
+// Insert puni tabelu iz p.Agg, ili posebnim prolazom kroz p.CSV ako tabela
+// nije agregirana.
+func (t Table) Insert(ctx context.Context, db *sql.DB, p Params) error {
+	res := p.Agg
+	if !res.Has(t.Name) {
+		var err error
+		if res, err = Aggregate(ctx, p.CSV, []Table{t}, 1); err != nil {
+			return err
+		}
+	}
+	if t.Optional && !res.hasCol(t.need) {
+		log.Printf("[SKIP] %s: ulaz nema kolonu %s", t.Name, t.need)
+		return nil
+	}
+	return t.write(ctx, db, p, res.states[t.Name])
+}
+
+// StatsTable: value_counts jedne CSV kolone u tabelu
+// (<ValueCol>, value, valueProp, month, year, project_id).
+type StatsTable struct {
+	Table    string // ln_genBotsMainStatsByX
+	Flag     string // geninsert --<Flag>
+	Column   string // CSV kolona (case-insensitive)
+	ValueCol string // kolona ključa u tabeli
+	MaxLen   int    // VARCHAR dužina ključa u runama (0 = bez skraćivanja)
+	Decimals int    // valueProp je decimal(6,Decimals)
+
+	// Canon (opciono) kanonizuje trimovanu vrednost; Filter (opciono) bira
+	// redove po kanonskoj vrednosti. Bez Filter-a se broje svi redovi, a
+	// prazna vrednost kao "(unknown)".
+	Canon  func(v string) string
+	Filter func(v string) bool
+
+	NoUpsert bool // INSERT bez ON DUPLICATE KEY UPDATE
+}
+
+func (s StatsTable) key(v string) (string, bool) {
+	if s.Canon != nil {
+		v = s.Canon(v)
+	}
+	if s.Filter != nil {
+		return v, s.Filter(v)
+	}
+	if v == "" {
+		return "(unknown)", true
+	}
+	return v, true
+}
+
+func (s StatsTable) sql() string {
+	q := fmt.Sprintf(`
+INSERT INTO %s
+(%s, value, valueProp, month, year, project_id)
+VALUES (?, ?, ?, ?, ?, ?)`, s.Table, s.ValueCol)
+	if !s.NoUpsert {
+		q += `
+ON DUPLICATE KEY UPDATE
+  value = VALUES(value),
+  valueProp = VALUES(valueProp)`
+	}
+	return q
+}
+
+func (s StatsTable) table() Table {
+	return Table{
+		Name:  s.Table,
+		Flag:  s.Flag,
+		spec:  dimSpec{[]string{s.Column}, newCounter(s.key)},
+		write: s.write,
+	}
+}
+
+func (s StatsTable) write(ctx context.Context, db *sql.DB, p Params, st dimState) error {
+	c := st.(*counter)
+	if c.total == 0 {
+		log.Printf("[WARN] %s: total=0 – nema redova za agregaciju", s.Table)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := tx.Rollback(); rerr != nil && rerr != sql.ErrTxDone {
+			log.Printf("[WARN] tx.Rollback failed: %v", rerr)
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, s.sql())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if serr := stmt.Close(); serr != nil {
+			log.Printf("[WARN] stmt.Close failed: %v", serr)
+		}
+	}()
+
+	for _, pkv := range c.sorted() {
+		prop := 0.0
+		if c.total > 0 {
+			prop = roundN((float64(pkv.Count)*100.0)/float64(c.total), s.Decimals)
+		}
+		name := pkv.Name
+		if s.MaxLen > 0 {
+			name = truncateRunes(name, s.MaxLen)
+		}
+		if _, err := stmt.ExecContext(ctx,
+			name, pkv.Count, prop,
+			p.Month, p.Year, p.ProjectID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// edgeTable: edge tabela (country/colo/cache) iz CSV kolone column; ključevi,
+// valueProp i upis su edgestats pravila (ista kao u ingest-u).
+func edgeTable(c edgestats.Counts, flag, column string) Table {
+	return Table{
+		Name:     c.Table,
+		Flag:     flag,
+		Optional: true,
+		need:     column,
+		spec:     dimSpec{[]string{column}, newCounter(c.Key)},
+		write: func(ctx context.Context, db *sql.DB, p Params, st dimState) error {
+			cnt := st.(*counter)
+			if cnt.total == 0 {
+				log.Printf("[SKIP] %s: nema redova sa %s", c.Table, column)
+				return nil
+			}
+			return c.Write(ctx, db, cnt.counts, p.period())
+		},
+	}
+}
+
+// isSitemapURL: URL liči na sitemap — sadrži "sitemap" ili se završava na ".xml".
+func isSitemapURL(v string) bool {
+	if v == "" {
+		return false
+	}
+	low := strings.ToLower(v)
+	return strings.Contains(low, "sitemap") || strings.HasSuffix(low, ".xml")
+}
+
+// tables: redosled je redosled upisa u geninsert.
+var tables = []Table{
+	{
+		Name:  "ln_genBotsMainStats",
+		Flag:  "main",
+		spec:  dimSpec{[]string{"botName"}, newCounter(edgestats.BotKey)},
+		write: writeMain,
+	},
+	StatsTable{Table: "ln_genBotsMainStatsBySource", Flag: "by-source",
+		Column: "source", ValueCol: "source", Decimals: 3}.table(),
+	StatsTable{Table: "ln_genBotsMainStatsByMethod", Flag: "by-method",
+		Column: "method", ValueCol: "method", Decimals: 3}.table(),
+	{
+		Name:  "ln_genBotsMainStatsByVerification",
+		Flag:  "by-verification",
+		spec:  dimSpec{[]string{"verified"}, func() dimState { return &verCounter{} }},
+		write: writeVerification,
+	},
+	// bez unique ključa: ostaje običan INSERT
+	StatsTable{Table: "ln_genBotsMainStatsByRefPage", Flag: "by-refpage",
+		Column: "referring_page", ValueCol: "url", MaxLen: 4050, Decimals: 2, NoUpsert: true}.table(),
+	StatsTable{Table: "ln_genBotsMainStatsByTarget", Flag: "by-target",
+		Column: "target", ValueCol: "target", MaxLen: 45, Decimals: 2}.table(),
+	StatsTable{Table: "ln_genBotsMainStatsByProtVersion", Flag: "by-protversion",
+		Column: "protocol", ValueCol: "protocol", MaxLen: 50, Decimals: 3}.table(),
+	StatsTable{Table: "ln_genBotsMainStatsBySitemap", Flag: "by-sitemap",
+		Column: "referring_page", ValueCol: "url", MaxLen: 4500, Decimals: 2, Filter: isSitemapURL}.table(),
+	edgeTable(edgestats.Country, "by-country", "country"),
+	edgeTable(edgestats.Colo, "by-colo", "edge_colo"),
+	edgeTable(edgestats.CacheStatus, "by-cache", "cache_status"),
+	{
+		Name:     edgestats.TTFBTable,
+		Flag:     "by-ttfb",
+		Optional: true,
+		need:     "ttfb_ms",
+		spec:     dimSpec{[]string{"botName", "ttfb_ms", "origin_time_ms"}, func() dimState { return timings{} }},
+		write:    writeTTFB,
+	},
+}
+
+// Tables vraća registar gen tabela (kopija, redosled upisa).
+func Tables() []Table {
+	return append([]Table(nil), tables...)
+}
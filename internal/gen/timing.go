@@ -0,0 +1,42 @@
+package gen
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"parser/internal/edgestats"
+)
+
+// ===== ln_genBotsMainStatsByTTFB =====
+// CSV kolone: "botName", "ttfb_ms", "origin_time_ms"; ključevi, percentili
+// i upis su u edgestats (isti kao u ingest-u).
+
+// timings: TTFB po botu (vals: botName, ttfb_ms, origin_time_ms).
+type timings edgestats.Timings
+
+func (ts timings) add(vals []string) {
+	ttfb, ok := edgestats.MS(vals[1])
+	if !ok {
+		return
+	}
+	bot, ok := edgestats.BotKey(vals[0])
+	if !ok {
+		return
+	}
+	origin, hasOrigin := edgestats.MS(vals[2])
+	edgestats.Timings(ts).Add(bot, ttfb, origin, hasOrigin)
+}
+
+func (ts timings) merge(other dimState) {
+	edgestats.Timings(ts).Merge(edgestats.Timings(other.(timings)))
+}
+
+func writeTTFB(ctx context.Context, db *sql.DB, p Params, st dimState) error {
+	ts := edgestats.Timings(st.(timings))
+	if len(ts) == 0 {
+		log.Printf("[SKIP] %s: nema redova sa ttfb_ms", edgestats.TTFBTable)
+		return nil
+	}
+	return edgestats.WriteTTFB(ctx, db, ts, p.period())
+}
@@ -1,6 +1,10 @@
 package aggregators
 
-import "time"
+import (
+	"time"
+
+	"parser/internal/edgestats"
+)
 
 type AggregateBucket struct {
 	FilteredRows int64
@@ -11,6 +15,12 @@ type AggregateBucket struct {
 
 	AIBotCounts map[string]int64 // "GPTBot", "PerplexityBot", ...
 
+	// Edge polja (prazno ako CSV nema kolone)
+	CountryCounts map[string]int64  // "US", "DE", ...
+	ColoCounts    map[string]int64  // "FRA", "IAD", ...
+	CacheCounts   map[string]int64  // "hit", "miss", "dynamic", ...
+	Timing        edgestats.Timings // botName -> TTFB/origin ms
+
 	// Meta isključivo za FILTRIRANE redove (target month/year)
 	MinTS time.Time
 	MaxTS time.Time
@@ -21,5 +31,15 @@ func NewAggregateBucket() *AggregateBucket {
 		MethodCounts: make(map[string]int64),
 		StatusCounts: make(map[string]int64),
 		AIBotCounts:  make(map[string]int64),
+
+		CountryCounts: make(map[string]int64),
+		ColoCounts:    make(map[string]int64),
+		CacheCounts:   make(map[string]int64),
+		Timing:        make(edgestats.Timings),
 	}
 }
+
+// AddTiming: jedan red sa TTFB vrednošću za bota (edgestats.BotKey).
+func (b *AggregateBucket) AddTiming(bot string, ttfb int64, origin int64, hasOrigin bool) {
+	b.Timing.Add(bot, ttfb, origin, hasOrigin)
+}
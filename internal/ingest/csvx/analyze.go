@@ -1,13 +1,12 @@
 package csvx
 
 import (
-	"bufio"
-	"encoding/csv"
 	"errors"
 	"io"
-	"os"
 	"strings"
 	"time"
+
+	"parser/internal/csvin"
 )
 
 type CSVStats struct {
@@ -21,23 +20,14 @@ type CSVStats struct {
 // AnalyzeCSV: prolazi kroz CSV i vraća month/year iz PRVOG validnog reda,
 // ukupan broj redova, i min/max timestamp.
 func AnalyzeCSV(path string) (*CSVStats, error) {
-	f, err := os.Open(path)
+	r, err := openTable(path, tsCandidates, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReaderSize(f, 1<<20)) // 1MB buffer
-	r.ReuseRecord = true
-	r.FieldsPerRecord = -1 // ne forsiramo fiksan broj kolona
+	defer r.Close()
 
-	// header
-	header, err := r.Read()
-	if err != nil {
-		return nil, err
-	}
-	tsIdx := detectTSColumn(header)
-	if tsIdx == -1 {
+	tsCol := r.ColAny(tsCandidates...)
+	if tsCol == csvin.NoCol {
 		return nil, errors.New("AnalyzeCSV: cannot find datetime/timestamp column")
 	}
 
@@ -47,18 +37,15 @@ func AnalyzeCSV(path string) (*CSVStats, error) {
 	)
 
 	for {
-		rec, err := r.Read()
+		// pokvarene linije preskače csvin (po uzoru na robustan streaming)
+		rec, err := r.NextRecord()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			// preskačemo pokvarene linije (po uzoru na robustan streaming)
-			continue
+			return nil, err
 		}
-		if tsIdx >= len(rec) {
-			continue
-		}
-		tsRaw := strings.TrimSpace(rec[tsIdx])
+		tsRaw := strings.TrimSpace(rec.At(tsCol))
 		if tsRaw == "" {
 			continue
 		}
@@ -92,18 +79,7 @@ func AnalyzeCSV(path string) (*CSVStats, error) {
 	return &stats, nil
 }
 
-func detectTSColumn(header []string) int {
-	cands := []string{"datetime", "timestamp", "time", "date"}
-	for i, h := range header {
-		hn := strings.ToLower(strings.TrimSpace(h))
-		for _, c := range cands {
-			if hn == c {
-				return i
-			}
-		}
-	}
-	return -1
-}
+var tsCandidates = []string{"datetime", "timestamp", "time", "date"}
 
 func parseTS(s string) (time.Time, bool) {
 	// Pokrivamo tipične formate iz tvojih fajlova
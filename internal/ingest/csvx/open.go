@@ -0,0 +1,26 @@
+package csvx
+
+import (
+	"parser/internal/csvin"
+	"parser/internal/iox"
+)
+
+// openTable otvara CSV (gzip/zstd) ili .parquet preko csvin i čita header.
+// cols su kandidati imena kolona koje pozivalac traži (ColAny); za Parquet
+// se čitaju samo one koje postoje (column pruning), CSV ih ignoriše.
+// onBad (opciono) se poziva za svaki preskočen loš red.
+func openTable(path string, cols []string, onBad func(error)) (*csvin.Reader, error) {
+	opt := csvin.Options{OnBadRow: onBad}
+	if iox.IsParquet(path) {
+		opt.Columns = cols
+	}
+	r, err := csvin.Open(path, opt)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := r.Header(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
@@ -1,13 +1,11 @@
 package csvx
 
 import (
-	"bufio"
-	"encoding/csv"
 	"io"
-	"os"
 	"strings"
 	"time"
 
+	"parser/internal/edgestats"
 	"parser/internal/ingest/aggregators"
 )
 
@@ -24,65 +22,80 @@ type DebugInfo struct {
 
 var simpleDateLayout = "2006-01-02 15:04:05"
 
-func normalizeName(s string) string { return strings.TrimSpace(strings.ToLower(s)) }
-
-func findCol(header []string, candidates ...string) int {
-	lh := make([]string, len(header))
-	for i, h := range header {
-		lh[i] = normalizeName(h)
-	}
-	for i := range candidates {
-		candidates[i] = normalizeName(candidates[i])
+// field: trimovana vrednost kolone ili "" (i < 0 = kolona ne postoji).
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
 	}
-	for i, h := range lh {
-		for _, c := range candidates {
-			if h == c {
-				return i
-			}
-		}
+	return strings.TrimSpace(record[i])
+}
+
+// Kandidati imena kolona (case-insensitive); streamColumns je njihova unija
+// (za Parquet se čitaju samo te kolone).
+var (
+	colDate    = []string{"datetime", "date", "time", "timestamp", "ts"}
+	colMethod  = []string{"method", "http_method"}
+	colStatus  = []string{"status", "status_code", "st_code", "code"}
+	colReq     = []string{"request", "path", "url", "target"}
+	colCountry = []string{"country", "client_country"}
+	colColo    = []string{"edge_colo", "colo"}
+	colCache   = []string{"cache_status"}
+	colTTFB    = []string{"ttfb_ms"}
+	colOrigin  = []string{"origin_time_ms"}
+	colBot     = []string{"botname"}
+
+	streamColumns = concat(colDate, colMethod, colStatus, colReq, colCountry, colColo, colCache, colTTFB, colOrigin, colBot)
+)
+
+func concat(lists ...[]string) []string {
+	var out []string
+	for _, l := range lists {
+		out = append(out, l...)
 	}
-	return -1
+	return out
 }
 
 // StreamAndAggregate: čita CSV, filtrira po mesecu/godini i puni agg.
 // Takođe postavlja agg.MinTS/MaxTS ISKLJUČIVO iz filtriranih redova (target mesec/godina).
 func StreamAndAggregate(csvPath string, month, year int, agg *aggregators.AggregateBucket, dbg *DebugInfo) error {
-	f, err := os.Open(csvPath)
-	if err != nil {
-		return err
+	var onBad func(error)
+	if dbg != nil {
+		onBad = func(error) { dbg.SkipParseErr++ }
 	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReaderSize(f, 1<<20)) // 1MB buffer
-	r.ReuseRecord = true
-	r.FieldsPerRecord = -1
-
-	header, err := r.Read()
+	r, err := openTable(csvPath, streamColumns, onBad)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
+
 	if dbg != nil {
+		header, _, _ := r.Header()
 		dbg.LastHeader = append([]string(nil), header...)
 	}
 
-	iDate := findCol(header, "datetime", "date", "time", "timestamp", "ts")
-	iMethod := findCol(header, "method", "http_method")
-	iStatus := findCol(header, "status", "status_code", "st_code", "code")
-	iReq := findCol(header, "request", "path", "url", "target")
+	// csvin.NoCol je -1: field() i provere ispod ga tretiraju kao "nema kolone"
+	iDate := int(r.ColAny(colDate...))
+	iMethod := int(r.ColAny(colMethod...))
+	iStatus := int(r.ColAny(colStatus...))
+	iReq := int(r.ColAny(colReq...))
+	iCountry := int(r.ColAny(colCountry...))
+	iColo := int(r.ColAny(colColo...))
+	iCache := int(r.ColAny(colCache...))
+	iTTFB := int(r.ColAny(colTTFB...))
+	iOrigin := int(r.ColAny(colOrigin...))
+	iBot := int(r.ColAny(colBot...))
 
 	firstFilteredSeen := false
 
 	for {
-		record, err := r.Read()
+		rec, err := r.NextRecord()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			if dbg != nil {
-				dbg.SkipParseErr++
-			}
-			continue
+			return err
 		}
+		record := rec.Fields()
 		if dbg != nil {
 			dbg.TotalRead++
 		}
@@ -175,6 +188,23 @@ func StreamAndAggregate(csvPath string, month, year int, agg *aggregators.Aggreg
 				agg.SitemapCount++
 			}
 		}
+
+		// edge polja (country/colo/cache/TTFB) — ključevi po edgestats pravilima (isto kao gen)
+		if k, ok := edgestats.Country.Key(field(record, iCountry)); ok {
+			agg.CountryCounts[k]++
+		}
+		if k, ok := edgestats.Colo.Key(field(record, iColo)); ok {
+			agg.ColoCounts[k]++
+		}
+		if k, ok := edgestats.CacheStatus.Key(field(record, iCache)); ok {
+			agg.CacheCounts[k]++
+		}
+		if ttfb, ok := edgestats.MS(field(record, iTTFB)); ok {
+			if bot, ok := edgestats.BotKey(field(record, iBot)); ok {
+				origin, hasOrigin := edgestats.MS(field(record, iOrigin))
+				agg.AddTiming(bot, ttfb, origin, hasOrigin)
+			}
+		}
 	}
 
 	return nil
@@ -11,13 +11,53 @@ import (
 // u trenutno selektovanoj bazi (SELECT DATABASE()).
 // Vraća: (hasRequired, hasAIBots, err).
 func Check(ctx context.Context, conn *sql.DB) (bool, bool, error) {
+	found, err := listTables(ctx, conn)
+	if err != nil {
+		return false, false, err
+	}
+
+	// 3) Obavezne i opciona
+	required := []string{
+		"logana_project",
+		"ln_genBotsMainStatsByMethod",
+		"ln_genRespCodes",
+	}
+	hasRequired := true
+	for _, t := range required {
+		if !found[t] {
+			hasRequired = false
+			break
+		}
+	}
+
+	hasAIBots := found["ln_aiBotHitsByName"]
+	return hasRequired, hasAIBots, nil
+}
+
+// HasTables: da li postoje SVE navedene tabele (npr. writer.EdgeTables).
+// Vraća i listu onih koje nedostaju, za log.
+func HasTables(ctx context.Context, conn *sql.DB, names []string) (bool, []string, error) {
+	found, err := listTables(ctx, conn)
+	if err != nil {
+		return false, nil, err
+	}
+	var missing []string
+	for _, t := range names {
+		if !found[t] {
+			missing = append(missing, t)
+		}
+	}
+	return len(missing) == 0, missing, nil
+}
+
+func listTables(ctx context.Context, conn *sql.DB) (map[string]bool, error) {
 	// 1) Aktivni schema
 	var dbName sql.NullString
 	if err := conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
-		return false, false, fmt.Errorf("SELECT DATABASE() failed: %w", err)
+		return nil, fmt.Errorf("SELECT DATABASE() failed: %w", err)
 	}
 	if !dbName.Valid || strings.TrimSpace(dbName.String) == "" {
-		return false, false, fmt.Errorf("n+o active database selected")
+		return nil, fmt.Errorf("n+o active database selected")
 	}
 	schema := dbName.String
 
@@ -29,7 +69,7 @@ func Check(ctx context.Context, conn *sql.DB) (bool, bool, error) {
 	`
 	rows, err := conn.QueryContext(ctx, q, schema)
 	if err != nil {
-		return false, false, fmt.Errorf("schema list query failed: %w", err)
+		return nil, fmt.Errorf("schema list query failed: %w", err)
 	}
 	defer rows.Close()
 
@@ -37,28 +77,12 @@ func Check(ctx context.Context, conn *sql.DB) (bool, bool, error) {
 	for rows.Next() {
 		var t string
 		if err := rows.Scan(&t); err != nil {
-			return false, false, fmt.Errorf("scan table failed: %w", err)
+			return nil, fmt.Errorf("scan table failed: %w", err)
 		}
 		found[t] = true
 	}
 	if err := rows.Err(); err != nil {
-		return false, false, fmt.Errorf("rows err: %w", err)
-	}
-
-	// 3) Obavezne i opciona
-	required := []string{
-		"logana_project",
-		"ln_genBotsMainStatsByMethod",
-		"ln_genRespCodes",
+		return nil, fmt.Errorf("rows err: %w", err)
 	}
-	hasRequired := true
-	for _, t := range required {
-		if !found[t] {
-			hasRequired = false
-			break
-		}
-	}
-
-	hasAIBots := found["ln_aiBotHitsByName"]
-	return hasRequired, hasAIBots, nil
+	return found, nil
 }
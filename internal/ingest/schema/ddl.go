@@ -0,0 +1,9 @@
+package schema
+
+import _ "embed"
+
+// EdgeDDL: CREATE TABLE za edge tabele (edgestats.Tables). Check/HasTables
+// ih ne prave; DDL se primenjuje ručno (mysql < edge_tables.sql).
+//
+//go:embed edge_tables.sql
+var EdgeDDL string
@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"parser/internal/edgestats"
+)
+
+// EdgeDDL pravi svaku edgestats tabelu sa kolonama koje edgestats upisuje.
+func TestEdgeDDL(t *testing.T) {
+	period := []string{"value", "month", "year", "project_id"}
+	want := map[string][]string{
+		edgestats.TTFBTable: append([]string{"botName", "avgTtfb", "p50Ttfb", "p95Ttfb", "avgOriginTime"}, period...),
+	}
+	for _, c := range []edgestats.Counts{edgestats.Country, edgestats.Colo, edgestats.CacheStatus} {
+		want[c.Table] = append([]string{c.Col, "valueProp"}, period...)
+	}
+
+	blocks := map[string]string{}
+	re := regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\)`)
+	for _, m := range re.FindAllStringSubmatch(EdgeDDL, -1) {
+		blocks[m[1]] = m[2]
+	}
+	if len(blocks) != len(edgestats.Tables) {
+		t.Errorf("EdgeDDL ima %d tabela, want %d", len(blocks), len(edgestats.Tables))
+	}
+	for _, table := range edgestats.Tables {
+		body, ok := blocks[table]
+		if !ok {
+			t.Errorf("EdgeDDL nema %s", table)
+			continue
+		}
+		cols := map[string]bool{}
+		for _, line := range strings.Split(body, "\n") {
+			if f := strings.Fields(line); len(f) > 1 && !strings.HasSuffix(f[0], "KEY") && f[0] != "PRIMARY" && f[0] != "UNIQUE" {
+				cols[f[0]] = true
+			}
+		}
+		for _, c := range want[table] {
+			if !cols[c] {
+				t.Errorf("%s: nema kolonu %s", table, c)
+			}
+		}
+		if !strings.Contains(body, "UNIQUE KEY") || !strings.Contains(body, "(project_id, year, month,") {
+			t.Errorf("%s: nema UNIQUE ključ meseca", table)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+-- Edge tabele (edgestats.Tables): pune ih ingest i geninsert kada CSV ima
+-- country/edge_colo/cache_status/ttfb_ms kolone. Upis je DELETE meseca
+-- (project_id, month, year) pa INSERT, pa je ključ meseca i UNIQUE.
+-- Tabele su opcione: bez njih se edge upis preskače ([SKIP] u logu).
+
+CREATE TABLE IF NOT EXISTS ln_genBotsMainStatsByCountry (
+  id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  country    VARCHAR(8)      NOT NULL, -- ClientCountry, UPPER (ISO 3166-1 alpha-2, XX, T1)
+  value      BIGINT          NOT NULL,
+  valueProp  DECIMAL(5,2)    NOT NULL, -- % od redova sa vrednošću
+  month      TINYINT         NOT NULL,
+  year       SMALLINT        NOT NULL,
+  project_id INT             NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_period_country (project_id, year, month, country)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS ln_genBotsMainStatsByColo (
+  id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  colo       VARCHAR(8)      NOT NULL, -- EdgeColoCode, UPPER (IATA)
+  value      BIGINT          NOT NULL,
+  valueProp  DECIMAL(5,2)    NOT NULL,
+  month      TINYINT         NOT NULL,
+  year       SMALLINT        NOT NULL,
+  project_id INT             NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_period_colo (project_id, year, month, colo)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS ln_genBotsMainStatsByCacheStatus (
+  id           BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  cache_status VARCHAR(32)     NOT NULL, -- CacheCacheStatus, lower (hit, miss, dynamic …)
+  value        BIGINT          NOT NULL,
+  valueProp    DECIMAL(5,2)    NOT NULL,
+  month        TINYINT         NOT NULL,
+  year         SMALLINT        NOT NULL,
+  project_id   INT             NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_period_cache_status (project_id, year, month, cache_status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS ln_genBotsMainStatsByTTFB (
+  id            BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  botName       VARCHAR(255)    NOT NULL, -- edgestats.BotKey
+  value         BIGINT          NOT NULL, -- redovi sa ttfb_ms
+  avgTtfb       DECIMAL(12,2)   NOT NULL, -- ms
+  p50Ttfb       BIGINT          NOT NULL, -- ms, nearest-rank
+  p95Ttfb       BIGINT          NOT NULL,
+  avgOriginTime DECIMAL(12,2)   NOT NULL, -- ms, samo redovi sa origin_time_ms
+  month         TINYINT         NOT NULL,
+  year          SMALLINT        NOT NULL,
+  project_id    INT             NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_period_bot (project_id, year, month, botName)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
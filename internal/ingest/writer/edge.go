@@ -0,0 +1,38 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+
+	"parser/internal/edgestats"
+)
+
+type EdgePayload struct {
+	ProjectID int
+	Month     int
+	Year      int
+
+	CountryCnt map[string]int64
+	ColoCnt    map[string]int64
+	CacheCnt   map[string]int64
+	Timing     edgestats.Timings
+}
+
+// Tabele koje InsertEdge puni (ista šema i pravila kao gen/geninsert).
+var EdgeTables = edgestats.Tables
+
+// InsertEdge: ključevi, valueProp, percentili i upis (DELETE meseca + INSERT)
+// su edgestats pravila; tabela bez ijednog ključa se ne dira.
+func InsertEdge(ctx context.Context, db *sql.DB, p EdgePayload) error {
+	per := edgestats.Period{ProjectID: int64(p.ProjectID), Month: p.Month, Year: p.Year}
+	if err := edgestats.Country.Write(ctx, db, p.CountryCnt, per); err != nil {
+		return err
+	}
+	if err := edgestats.Colo.Write(ctx, db, p.ColoCnt, per); err != nil {
+		return err
+	}
+	if err := edgestats.CacheStatus.Write(ctx, db, p.CacheCnt, per); err != nil {
+		return err
+	}
+	return edgestats.WriteTTFB(ctx, db, p.Timing, per)
+}
@@ -1,38 +1,135 @@
 package iox
 
 import (
-	"compress/gzip"
+	"bufio"
+	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+// IsParquet: .parquet izlaz/ulaz (csvout.NewFor, csvin.Open) umesto CSV-a.
+func IsParquet(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".parquet")
+}
+
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
 )
 
+const (
+	readBuf  = 1 << 20 // bufio ispred dekompresora
+	gzBlock  = 1 << 20 // pgzip blok po gorutini
+	magicLen = 4       // najduži magic (zstd)
+)
+
+// Stdio: "-" kao putanja znači stdin (OpenAuto) odnosno stdout (CreateAuto).
+const Stdio = "-"
+
+func IsStdio(path string) bool { return path == Stdio }
+
+// OpenAuto otvara fajl i dekompresuje ga ako počinje gzip ili zstd magic
+// bajtovima (ekstenzija se ne gleda: raw.csv može biti .gz i obrnuto).
 func OpenAuto(path string) (io.ReadCloser, error) {
+	if IsStdio(path) {
+		r, err := Decompress(io.NopCloser(os.Stdin))
+		if err != nil {
+			return nil, fmt.Errorf("stdin: %w", err)
+		}
+		return r, nil
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	if filepath.Ext(path) == ".gz" {
-		gr, err := gzip.NewReader(f)
+	r, err := Decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Decompress: isto kao OpenAuto za već otvoren ulaz (npr. stdin).
+// Close zatvara i src.
+func Decompress(src io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(src, readBuf)
+	head, err := br.Peek(magicLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		// pgzip dekompresuje unapred u posebnoj gorutini (read-ahead)
+		gr, err := pgzip.NewReader(br)
 		if err != nil {
-			f.Close()
 			return nil, err
 		}
-		return &rc{Reader: gr, Closers: []io.Closer{gr, f}}, nil
+		return &rc{Reader: gr, Closers: []io.Closer{gr, src}}, nil
+	case bytes.HasPrefix(head, magicZstd):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		zc := zr.IOReadCloser()
+		return &rc{Reader: zc, Closers: []io.Closer{zc, src}}, nil
 	}
-	return f, nil
+	return &rc{Reader: br, Closers: []io.Closer{src}}, nil
 }
 
+// CreateAuto bira kompresiju po ekstenziji: .gz (pgzip, paralelno po
+// blokovima) ili .zst (zstd, paralelni encoder); ostalo je nekompresovano.
+// Stdout ("-") je uvek nekompresovan (kompresiju radi sledeći proces u pipe-u).
 func CreateAuto(path string) (io.WriteCloser, error) {
+	if IsStdio(path) {
+		return nopWriteCloser{os.Stdout}, nil
+	}
 	f, err := os.Create(path)
 	if err != nil {
 		return nil, err
 	}
-	if filepath.Ext(path) == ".gz" {
-		gw := gzip.NewWriter(f)
-		return &wc{Writer: gw, Closers: []io.Closer{gw, f}}, nil
+	w, err := Compress(f, path)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return w, nil
+}
+
+// Compress: isto kao CreateAuto za već otvoren izlaz; name određuje format.
+// Close zatvara i dst.
+func Compress(dst io.WriteCloser, name string) (io.WriteCloser, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz":
+		gw := pgzip.NewWriter(dst)
+		if err := gw.SetConcurrency(gzBlock, runtime.GOMAXPROCS(0)); err != nil {
+			return nil, err
+		}
+		return &wc{Writer: gw, Closers: []io.Closer{gw, dst}}, nil
+	case ".zst":
+		zw, err := zstd.NewWriter(dst, zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0)))
+		if err != nil {
+			return nil, err
+		}
+		return &wc{Writer: zw, Closers: []io.Closer{zw, dst}}, nil
+	}
+	return dst, nil
+}
+
+// TrimCompressExt skida .gz/.zst sa imena (npr. za izvedena imena fajlova).
+func TrimCompressExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".zst":
+		return path[:len(path)-len(filepath.Ext(path))]
 	}
-	return f, nil
+	return path
 }
 
 type rc struct {
@@ -64,3 +161,8 @@ func (w *wc) Close() error {
 	}
 	return err
 }
+
+// nopWriteCloser: stdout se ne zatvara (log i drugi pisci ga i dalje koriste).
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,167 @@
+package iox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// payload: više pgzip blokova (gzBlock) da bi se proverilo paralelno pakovanje.
+func payload() []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < 3*gzBlock; i++ {
+		fmt.Fprintf(&b, "%d,66.249.66.%d,Googlebot,/p/%d\n", i, i%256, i*7)
+	}
+	return b.Bytes()
+}
+
+func readAll(t *testing.T, path string) []byte {
+	t.Helper()
+	r, err := OpenAuto(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := payload()
+	cases := []struct {
+		name  string
+		magic []byte // nil → nekompresovano
+	}{
+		{"out.csv", nil},
+		{"out.csv.gz", magicGzip},
+		{"out.csv.GZ", magicGzip},
+		{"out.csv.zst", magicZstd},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.name)
+			w, err := CreateAuto(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.magic == nil {
+				if !bytes.Equal(raw, data) {
+					t.Fatal("nekompresovan fajl se razlikuje od upisanog")
+				}
+			} else if !bytes.HasPrefix(raw, tc.magic) || len(raw) >= len(data) {
+				t.Fatalf("fajl nije kompresovan (%d/%d bajtova, počinje sa % x)", len(raw), len(data), raw[:4])
+			}
+			if got := readAll(t, path); !bytes.Equal(got, data) {
+				t.Fatalf("pročitano %d bajtova, want %d (isti sadržaj)", len(got), len(data))
+			}
+		})
+	}
+}
+
+// OpenAuto gleda magic bajtove, ne ekstenziju.
+func TestOpenAutoMagic(t *testing.T) {
+	data := []byte("host_ip,botName\n66.249.66.1,Googlebot\n")
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(data)
+	gw.Close()
+
+	var zs bytes.Buffer
+	zw, err := zstd.NewWriter(&zs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(data)
+	zw.Close()
+
+	cases := []struct {
+		name string
+		raw  []byte
+		want []byte
+	}{
+		{"raw.csv.gz", gz.Bytes(), data},
+		{"raw.csv", gz.Bytes(), data}, // gzip bez .gz
+		{"raw.csv.zst", zs.Bytes(), data},
+		{"zstd.csv.gz", zs.Bytes(), data}, // zstd pod .gz ekstenzijom
+		{"plain.csv.gz", data, data},      // nekompresovan fajl sa .gz
+		{"plain.csv", data, data},
+		{"kratak.csv", []byte("a"), []byte("a")}, // kraći od magicLen
+		{"prazan.csv", nil, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.name)
+			if err := os.WriteFile(path, tc.raw, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := readAll(t, path); !bytes.Equal(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+type failCloser struct {
+	bytes.Buffer
+	err error
+}
+
+func (f *failCloser) Close() error { return f.err }
+
+// Greška pri zatvaranju odredišta (npr. pun disk) mora da izađe iz Close,
+// i posle uspešnog flush-a kompresora.
+func TestCompressCloseError(t *testing.T) {
+	errDisk := errors.New("disk full")
+	for _, name := range []string{"x.csv", "x.csv.gz", "x.csv.zst"} {
+		dst := &failCloser{err: errDisk}
+		w, err := Compress(dst, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("a,b\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); !errors.Is(err, errDisk) {
+			t.Errorf("%s: Close = %v, want %v", name, err, errDisk)
+		}
+	}
+}
+
+func TestTrimCompressExt(t *testing.T) {
+	cases := map[string]string{
+		"out.csv.gz":  "out.csv",
+		"out.csv.ZST": "out.csv",
+		"out.csv":     "out.csv",
+		"out.parquet": "out.parquet",
+		"-":           "-",
+	}
+	for in, want := range cases {
+		if got := TrimCompressExt(in); got != want {
+			t.Errorf("TrimCompressExt(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
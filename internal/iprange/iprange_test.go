@@ -0,0 +1,83 @@
+package iprange
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tb := New()
+	for _, r := range []struct{ cidr, name string }{
+		{"66.249.64.0/19", "Googlebot"},
+		{"66.249.66.0/24", "Googlebot-Image"}, // duži prefiks pobeđuje
+		{"40.77.167.0/24", "Bingbot"},
+		{"2001:4860:4801::/48", "Googlebot"},
+		{"40.77.167.0/24", "bingbot"}, // isti prefiks: prepisuje labelu
+	} {
+		tb.Insert(netip.MustParsePrefix(r.cidr), r.name)
+	}
+	if tb.Len() != 4 {
+		t.Errorf("Len = %d, want 4", tb.Len())
+	}
+
+	cases := []struct {
+		ip, name string
+		ok       bool
+	}{
+		{"66.249.65.1", "Googlebot", true},
+		{"66.249.66.1", "Googlebot-Image", true},
+		{"40.77.167.9", "bingbot", true},
+		{"::ffff:66.249.65.1", "Googlebot", true}, // IPv4-mapped
+		{"[2001:4860:4801:10::1]", "Googlebot", true},
+		{" 66.249.96.1 ", "", false},
+		{"2001:db8::1", "", false},
+		{"nije ip", "", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		name, ok := tb.LookupString(tc.ip)
+		if name != tc.name || ok != tc.ok {
+			t.Errorf("LookupString(%q) = (%q, %v), want (%q, %v)", tc.ip, name, ok, tc.name, tc.ok)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	want := []netip.Prefix{
+		netip.MustParsePrefix("66.249.64.0/27"),
+		netip.MustParsePrefix("2001:4860:4801:10::/64"),
+		netip.MustParsePrefix("1.2.3.4/32"),
+	}
+	cases := []struct {
+		name, data string
+	}{
+		{"published JSON", `{"creationTime":"x","prefixes":[{"ipv4Prefix":"66.249.64.0/27"},{"ipv6Prefix":"2001:4860:4801:10::/64"},{"ipv4Prefix":"1.2.3.4"}]}`},
+		{"JSON niz", `["66.249.64.0/27", "2001:4860:4801:10::/64", "1.2.3.4"]`},
+		{"tekst", "# Googlebot\n66.249.64.0/27\n\n2001:4860:4801:10::/64  # v6\n1.2.3.4\n"},
+	}
+	dir := t.TempDir()
+	for _, tc := range cases {
+		path := filepath.Join(dir, "ranges")
+		if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v, want %v", tc.name, got, want)
+		}
+	}
+
+	path := filepath.Join(dir, "bad")
+	if err := os.WriteFile(path, []byte("66.249.64.0/27\n300.1.1.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile sa neispravnim opsegom: očekivana greška")
+	}
+}
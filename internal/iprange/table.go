@@ -0,0 +1,181 @@
+package iprange
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Table: binarni prefiksni trie (posebno za IPv4 i IPv6) sa longest-prefix
+// match pretragom. Lookup je O(broj bitova adrese), nezavisno od broja opsega.
+type Table struct {
+	v4, v6 *node
+	n      int
+}
+
+type node struct {
+	child [2]*node
+	name  string
+	set   bool
+}
+
+func New() *Table {
+	return &Table{v4: &node{}, v6: &node{}}
+}
+
+// Len vraća broj ubačenih prefiksa.
+func (t *Table) Len() int { return t.n }
+
+// Insert dodaje prefiks sa labelom (npr. ime bota). Kasniji insert istog
+// prefiksa prepisuje labelu.
+func (t *Table) Insert(p netip.Prefix, name string) {
+	p = p.Masked()
+	addr := p.Addr()
+	root := t.v6
+	if addr.Is4() {
+		root = t.v4
+	}
+	b := addr.AsSlice()
+	cur := root
+	for i := 0; i < p.Bits(); i++ {
+		bit := (b[i/8] >> (7 - uint(i%8))) & 1
+		if cur.child[bit] == nil {
+			cur.child[bit] = &node{}
+		}
+		cur = cur.child[bit]
+	}
+	if !cur.set {
+		t.n++
+	}
+	cur.name, cur.set = name, true
+}
+
+// Lookup vraća labelu najdužeg prefiksa koji sadrži ip.
+func (t *Table) Lookup(ip netip.Addr) (string, bool) {
+	if !ip.IsValid() {
+		return "", false
+	}
+	ip = ip.Unmap()
+	cur := t.v6
+	if ip.Is4() {
+		cur = t.v4
+	}
+	b := ip.AsSlice()
+	name, found := "", false
+	for i := 0; cur != nil; i++ {
+		if cur.set {
+			name, found = cur.name, true
+		}
+		if i >= len(b)*8 {
+			break
+		}
+		bit := (b[i/8] >> (7 - uint(i%8))) & 1
+		cur = cur.child[bit]
+	}
+	return name, found
+}
+
+// LookupString je Lookup za string IP ("66.249.66.1", "[2001:db8::1]").
+func (t *Table) LookupString(ip string) (string, bool) {
+	a, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(ip), "[]"))
+	if err != nil {
+		return "", false
+	}
+	return t.Lookup(a)
+}
+
+// LoadFile čita listu opsega sa diska. Podržani formati:
+//   - JSON kakav objavljuju Google/Bing/OpenAI/Perplexity/Apple:
+//     {"prefixes":[{"ipv4Prefix":"66.249.64.0/27"},{"ipv6Prefix":"2001:4860:4801:10::/64"}]}
+//   - JSON niz stringova: ["66.249.64.0/27", ...]
+//   - tekst: jedan CIDR (ili IP) po liniji, '#' za komentare
+func LoadFile(path string) ([]netip.Prefix, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return parseJSON(trimmed)
+	}
+	return parseText(trimmed)
+}
+
+type publishedList struct {
+	Prefixes []struct {
+		IPv4 string `json:"ipv4Prefix"`
+		IPv6 string `json:"ipv6Prefix"`
+	} `json:"prefixes"`
+}
+
+func parseJSON(b []byte) ([]netip.Prefix, error) {
+	var raw []string
+	if b[0] == '[' {
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var pl publishedList
+		if err := json.Unmarshal(b, &pl); err != nil {
+			return nil, err
+		}
+		for _, p := range pl.Prefixes {
+			if p.IPv4 != "" {
+				raw = append(raw, p.IPv4)
+			}
+			if p.IPv6 != "" {
+				raw = append(raw, p.IPv6)
+			}
+		}
+	}
+	out := make([]netip.Prefix, 0, len(raw))
+	for _, s := range raw {
+		p, err := parsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func parseText(b []byte) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	line := 0
+	for sc.Scan() {
+		line++
+		s := sc.Text()
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s = s[:i]
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		p, err := parsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, p)
+	}
+	return out, sc.Err()
+}
+
+// parsePrefix prihvata i gole IP adrese (tretira ih kao /32 ili /128).
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
@@ -0,0 +1,327 @@
+package mapper
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"parser/internal/botdetector"
+	"parser/internal/schema"
+	"parser/internal/source"
+)
+
+//go:embed default_spec.yaml
+var defaultSpecYAML []byte
+
+// Spec je deklarativni opis normalize faze: koje izlazne kolone postoje,
+// iz kojih izvornih polja se pune i kojim transformacijama (vidi default_spec.yaml).
+type Spec struct {
+	Columns []ColumnSpec                 `json:"columns" yaml:"columns"`
+	Lookups map[string]map[string]string `json:"lookups,omitempty" yaml:"lookups,omitempty"`
+}
+
+type ColumnSpec struct {
+	Name      string   `json:"name" yaml:"name"`
+	From      []string `json:"from,omitempty" yaml:"from,omitempty"`
+	Default   string   `json:"default,omitempty" yaml:"default,omitempty"`
+	Transform []Step   `json:"transform,omitempty" yaml:"transform,omitempty"`
+}
+
+// Step je jedna transformacija; u YAML/JSON može i kao goli string ("lower").
+type Step struct {
+	Op      string   `json:"op" yaml:"op"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty"`       // absolute_url, scheme: izvorna polja
+	In      string   `json:"in,omitempty" yaml:"in,omitempty"`           // time: rfc3339 (default) | auto | unix | unix_ms | unix_ns | Go layout
+	Out     string   `json:"out,omitempty" yaml:"out,omitempty"`         // time: izlazni layout ili rfc3339|day|month|year
+	Table   string   `json:"table,omitempty" yaml:"table,omitempty"`     // lookup: ime tabele
+	Default string   `json:"default,omitempty" yaml:"default,omitempty"` // lookup: vrednost za nepoznat ključ
+	Factor  float64  `json:"factor,omitempty" yaml:"factor,omitempty"`   // scale: množilac (1e-6 za ns → ms)
+}
+
+func (s *Step) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		s.Op = n.Value
+		return nil
+	}
+	type plain Step
+	return n.Decode((*plain)(s))
+}
+
+func (s *Step) UnmarshalJSON(b []byte) error {
+	var op string
+	if err := json.Unmarshal(b, &op); err == nil {
+		s.Op = op
+		return nil
+	}
+	type plain Step
+	return json.Unmarshal(b, (*plain)(s))
+}
+
+// Mapper je kompajliran Spec.
+type Mapper struct {
+	header []string
+	cols   []compiledCol
+	fields []string // izvorna polja koja spec čita
+}
+
+// compiledCol: izvorna polja su indeksi u Mapper.fields (vidi MapValues).
+type compiledCol struct {
+	from  []int
+	def   string
+	steps []stepFunc
+}
+
+// stepFunc: v je trenutna vrednost kolone, src izvorni red po SourceFields().
+type stepFunc func(v string, src []string) string
+
+var defaultMapper = mustDefault()
+
+func mustDefault() *Mapper {
+	m, err := parseSpec(defaultSpecYAML, ".yaml")
+	if err != nil {
+		panic("mapper: default spec: " + err.Error())
+	}
+	return m
+}
+
+// Default vraća mapper iz ugrađenog default_spec.yaml.
+func Default() *Mapper { return defaultMapper }
+
+// LoadSpec učitava spec iz .yaml/.yml/.json fajla.
+func LoadSpec(path string) (*Mapper, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseSpec(b, strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		return nil, fmt.Errorf("map spec %q: %w", path, err)
+	}
+	return m, nil
+}
+
+func parseSpec(b []byte, ext string) (*Mapper, error) {
+	var s Spec
+	var err error
+	if ext == ".json" {
+		err = json.Unmarshal(b, &s)
+	} else {
+		err = yaml.Unmarshal(b, &s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Compile(s)
+}
+
+// Compile proverava spec i pravi Mapper. Izlazni header je uvek
+// schema.BaseHeader() (kolone koje spec ne puni ostaju prazne), a kolone
+// van BaseColumns se dodaju na kraj redosledom iz speca.
+func Compile(s Spec) (*Mapper, error) {
+	if len(s.Columns) == 0 {
+		return nil, errors.New("spec has no columns")
+	}
+	byName := make(map[string]ColumnSpec, len(s.Columns))
+	var extra []string
+	for _, c := range s.Columns {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return nil, errors.New("column without name")
+		}
+		if _, dup := byName[c.Name]; dup {
+			return nil, fmt.Errorf("duplicate column %q", c.Name)
+		}
+		byName[c.Name] = c
+		if !schema.IsBase(c.Name) {
+			extra = append(extra, c.Name)
+		}
+	}
+
+	m := &Mapper{header: append(schema.BaseHeader(), extra...)}
+	ids := make(map[string]int)
+	addField := func(f string) int {
+		if id, ok := ids[f]; ok {
+			return id
+		}
+		ids[f] = len(m.fields)
+		m.fields = append(m.fields, f)
+		return ids[f]
+	}
+	for _, name := range m.header {
+		c, ok := byName[name]
+		if !ok {
+			m.cols = append(m.cols, compiledCol{})
+			continue
+		}
+		cc := compiledCol{def: c.Default}
+		for _, f := range c.From {
+			if f != "" {
+				cc.from = append(cc.from, addField(f))
+			}
+		}
+		for _, st := range c.Transform {
+			args := make([]int, len(st.Args))
+			for i, a := range st.Args {
+				if a == "" {
+					return nil, fmt.Errorf("column %q: %s: empty arg", c.Name, st.Op)
+				}
+				args[i] = addField(a)
+			}
+			fn, err := compileStep(st, args, s.Lookups)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", c.Name, err)
+			}
+			cc.steps = append(cc.steps, fn)
+		}
+		m.cols = append(m.cols, cc)
+	}
+	return m, nil
+}
+
+// compileStep: args su indeksi st.Args polja u src (Mapper.fields).
+func compileStep(st Step, args []int, lookups map[string]map[string]string) (stepFunc, error) {
+	switch strings.ToLower(strings.TrimSpace(st.Op)) {
+	case "lower":
+		return func(v string, _ []string) string { return strings.ToLower(v) }, nil
+	case "upper":
+		return func(v string, _ []string) string { return strings.ToUpper(v) }, nil
+	case "trim":
+		return func(v string, _ []string) string { return strings.TrimSpace(v) }, nil
+	case "flag":
+		return func(v string, _ []string) string {
+			if v = strings.TrimSpace(v); v != "" && v != "-" {
+				return "1"
+			}
+			return ""
+		}, nil
+	case "bot":
+		return func(v string, _ []string) string {
+			if b, ok := botdetector.MatchUA(v); ok {
+				return b.Name
+			}
+			return ""
+		}, nil
+	case "time":
+		in := st.In
+		if in == "" {
+			in = "rfc3339"
+		}
+		out := st.Out
+		if out == "" {
+			return nil, errors.New("time: out is required")
+		}
+		return func(v string, _ []string) string {
+			if v == "" {
+				return ""
+			}
+			t, ok := source.ParseTime(v, in)
+			if !ok {
+				return ""
+			}
+			switch strings.ToLower(out) {
+			case "rfc3339":
+				// offset ulaza ostaje (accesslog --log-tz), kao za time_zone/day/month/year
+				return t.Format(time.RFC3339Nano)
+			case "day":
+				return strconv.Itoa(t.Day())
+			case "month":
+				return strconv.Itoa(int(t.Month()))
+			case "year":
+				return strconv.Itoa(t.Year())
+			}
+			return t.Format(out)
+		}, nil
+	case "absolute_url":
+		if len(st.Args) != 4 {
+			return nil, errors.New("absolute_url: args must be [uri, host, scheme, referer]")
+		}
+		a := args
+		return func(_ string, src []string) string {
+			return absoluteFrom(src[a[0]], src[a[1]], src[a[2]], src[a[3]])
+		}, nil
+	case "scheme":
+		if len(st.Args) != 2 {
+			return nil, errors.New("scheme: args must be [scheme, referer]")
+		}
+		a := args
+		return func(_ string, src []string) string {
+			return pickScheme(src[a[0]], src[a[1]])
+		}, nil
+	case "scale":
+		if st.Factor == 0 {
+			return nil, errors.New("scale: factor is required")
+		}
+		f := st.Factor
+		return func(v string, _ []string) string {
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return ""
+			}
+			return strconv.FormatInt(int64(math.Round(n*f)), 10)
+		}, nil
+	case "lookup":
+		tbl, ok := lookups[st.Table]
+		if !ok {
+			return nil, fmt.Errorf("lookup: unknown table %q", st.Table)
+		}
+		def := st.Default
+		return func(v string, _ []string) string {
+			if r, ok := tbl[v]; ok {
+				return r
+			}
+			if def != "" {
+				return def
+			}
+			return v
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", st.Op)
+}
+
+// Header: schema.BaseHeader() + dodatne kolone iz speca.
+func (m *Mapper) Header() []string { return append([]string(nil), m.header...) }
+
+// SourceFields: izvorna polja koja spec čita (redosled iz speca).
+func (m *Mapper) SourceFields() []string { return append([]string(nil), m.fields...) }
+
+// Map: izvorni red (kanonska imena) → red u Header() redosledu.
+func (m *Mapper) Map(src map[string]string) []string {
+	vals := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		vals[i] = src[f]
+	}
+	return m.MapValues(vals, nil)
+}
+
+// MapValues je Map bez mape: vals su vrednosti izvornih polja redosledom
+// SourceFields(). Rezultat se upisuje u out (append na out[:0]), pa pozivalac
+// koji reciklira out i vals ne alocira po redu. Bezbedno za konkurentne
+// pozive sa različitim vals/out.
+func (m *Mapper) MapValues(vals, out []string) []string {
+	out = out[:0]
+	for _, c := range m.cols {
+		v := ""
+		for _, f := range c.from {
+			if v = vals[f]; v != "" {
+				break
+			}
+		}
+		if v == "" {
+			v = c.def
+		}
+		for _, fn := range c.steps {
+			v = fn(v, vals)
+		}
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package mapper
+
+import (
+	"testing"
+
+	"parser/internal/schema"
+)
+
+// EdgeEndTimestamp u svim Logpush formatima daje iste vremenske kolone, a
+// datetime prolazi schema proveru (TimeISO).
+func TestDefaultSpecTimestamps(t *testing.T) {
+	want := map[string]string{
+		"time_zone": "2025-09-01 10:00:00",
+		"day":       "1",
+		"month":     "9",
+		"year":      "2025",
+		"datetime":  "2025-09-01T10:00:00Z",
+	}
+	cases := []struct {
+		name, ts, datetime string
+	}{
+		{"rfc3339", "2025-09-01T10:00:00Z", ""},
+		{"unix", "1756720800", ""},
+		{"unixnano", "1756720800000000000", ""},
+		{"unixnano sa ostatkom", "1756720800000000123", "2025-09-01T10:00:00.000000123Z"},
+		{"unix ms", "1756720800000", ""},
+	}
+
+	m := Default()
+	header := m.Header()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			row := m.Map(map[string]string{"EdgeEndTimestamp": tc.ts})
+			for i, col := range header {
+				w, ok := want[col]
+				if !ok {
+					continue
+				}
+				if col == "datetime" && tc.datetime != "" {
+					w = tc.datetime
+				}
+				if row[i] != w {
+					t.Errorf("%s = %q, want %q", col, row[i], w)
+				}
+				if !schema.KindOf(col).Valid(row[i]) {
+					t.Errorf("%s = %q nije validan %s", col, row[i], schema.KindOf(col))
+				}
+			}
+		})
+	}
+}
+
+// Timestamp sa offsetom (accesslog --log-tz): sve vremenske kolone su u istoj
+// zoni, i preko granice dana.
+func TestDefaultSpecTimestampZone(t *testing.T) {
+	want := map[string]string{
+		"time_zone": "2025-08-31 23:30:00",
+		"day":       "31",
+		"month":     "8",
+		"year":      "2025",
+		"datetime":  "2025-08-31T23:30:00-07:00",
+	}
+	m := Default()
+	row := m.Map(map[string]string{"EdgeEndTimestamp": "2025-08-31T23:30:00-07:00"})
+	for i, col := range m.Header() {
+		if w, ok := want[col]; ok && row[i] != w {
+			t.Errorf("%s = %q, want %q", col, row[i], w)
+		}
+	}
+}
+
+func TestTimeStep(t *testing.T) {
+	cases := []struct {
+		step    Step
+		in, out string
+	}{
+		{Step{Op: "time", Out: "year"}, "2025-09-01T10:00:00Z", "2025"},
+		{Step{Op: "time", In: "rfc3339", Out: "rfc3339"}, "2025-09-01T12:00:00+02:00", "2025-09-01T12:00:00+02:00"},
+		{Step{Op: "time", Out: "year"}, "1756720800", ""}, // rfc3339 (podrazumevano) ne prima unix
+		{Step{Op: "time", In: "unix", Out: "rfc3339"}, "1756720800", "2025-09-01T10:00:00Z"},
+		{Step{Op: "time", In: "unix_ns", Out: "2006-01-02"}, "1756720800000000000", "2025-09-01"},
+		{Step{Op: "time", In: "02/Jan/2006:15:04:05 -0700", Out: "rfc3339"}, "01/Sep/2025:12:00:00 +0200", "2025-09-01T12:00:00+02:00"},
+		{Step{Op: "time", In: "auto", Out: "day"}, "nije vreme", ""},
+		{Step{Op: "time", In: "auto", Out: "day"}, "", ""},
+	}
+	for _, tc := range cases {
+		fn, err := compileStep(tc.step, nil, nil)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.step, err)
+		}
+		if got := fn(tc.in, nil); got != tc.out {
+			t.Errorf("%+v(%q) = %q, want %q", tc.step, tc.in, got, tc.out)
+		}
+	}
+}
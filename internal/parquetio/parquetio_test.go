@@ -0,0 +1,165 @@
+package parquetio
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"parser/internal/schema"
+)
+
+// baseRow: red u schema.BaseHeader() redosledu iz mape kolona (ostale "").
+func baseRow(vals map[string]string) []string {
+	h := schema.BaseHeader()
+	row := make([]string, len(h))
+	for i, c := range h {
+		row[i] = vals[c]
+	}
+	return row
+}
+
+func writeFile(t *testing.T, header []string, rows [][]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if err := w.WriteRow(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, path string, columns []string) ([]string, [][]string) {
+	t.Helper()
+	r, err := Open(path, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, rec)
+	}
+	return r.Header(), rows
+}
+
+func TestRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		in, out map[string]string // out nil → isto kao in
+	}{
+		{
+			name: "pun red",
+			in: map[string]string{
+				"host_ip": "66.249.66.1", "time_zone": "2025-09-01 10:00:00", "status_code": "200",
+				"size": "5117", "user_agent": "Mozilla/5.0 (compatible; Googlebot/2.1)", "method": "GET",
+				"referring_page": "https://example.com/p?a=1,b=2", "day": "1", "month": "9", "year": "2025",
+				"botName": "Googlebot", "verified": "1", "datetime": "2025-09-01T10:00:00Z",
+				"verify_method": "fcrdns", "spoofed": "0", "country": "US", "edge_colo": "FRA",
+				"cache_status": "hit", "ttfb_ms": "12", "origin_time_ms": "0",
+			},
+		},
+		{
+			name: "prazne vrednosti su NULL",
+			in:   map[string]string{"host_ip": "1.2.3.4"},
+		},
+		{
+			name: "datetime sa mikrosekundama",
+			in:   map[string]string{"datetime": "2025-09-01T10:00:00.123456Z", "spoofed": "1"},
+		},
+		{
+			name: "datetime sa zonom se čita u UTC",
+			in:   map[string]string{"datetime": "2025-09-01T12:00:00+02:00"},
+			out:  map[string]string{"datetime": "2025-09-01T10:00:00Z"},
+		},
+		{
+			name: "nanosekunde se odsecaju na mikrosekunde",
+			in:   map[string]string{"datetime": "2025-09-01T10:00:00.000001999Z"},
+			out:  map[string]string{"datetime": "2025-09-01T10:00:00.000001Z"},
+		},
+		{
+			name: "bool true/false",
+			in:   map[string]string{"spoofed": "true"},
+			out:  map[string]string{"spoofed": "1"},
+		},
+		{
+			name: "vrednost koja nije Kind postaje NULL",
+			in:   map[string]string{"datetime": "1756720800", "status_code": "abc", "spoofed": "maybe", "botName": "x"},
+			out:  map[string]string{"botName": "x"},
+		},
+	}
+
+	var in, want [][]string
+	for _, tc := range cases {
+		in = append(in, baseRow(tc.in))
+		out := tc.out
+		if out == nil {
+			out = tc.in
+		}
+		want = append(want, baseRow(out))
+	}
+	path := writeFile(t, schema.BaseHeader(), in)
+
+	header, got := readAll(t, path, nil)
+	if !reflect.DeepEqual(header, schema.BaseHeader()) {
+		t.Fatalf("header = %v, want %v", header, schema.BaseHeader())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rows = %d, want %d", len(got), len(want))
+	}
+	h := schema.BaseHeader()
+	for i, tc := range cases {
+		for j := range h {
+			if got[i][j] != want[i][j] {
+				t.Errorf("%s: %s = %q, want %q", tc.name, h[j], got[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestReadColumns(t *testing.T) {
+	header := []string{"host_ip", "status_code", "datetime"}
+	path := writeFile(t, header, [][]string{
+		{"1.2.3.4", "200", "2025-09-01T10:00:00Z"},
+		{"5.6.7.8", "", "2025-09-02T10:00:00Z"},
+	})
+
+	h, rows := readAll(t, path, []string{"datetime", "host_ip"})
+	if want := []string{"datetime", "host_ip"}; !reflect.DeepEqual(h, want) {
+		t.Fatalf("header = %v, want %v", h, want)
+	}
+	want := [][]string{
+		{"2025-09-01T10:00:00Z", "1.2.3.4"},
+		{"2025-09-02T10:00:00Z", "5.6.7.8"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %v, want %v", rows, want)
+	}
+
+	if _, err := Open(path, []string{"nema"}); err == nil {
+		t.Fatal("Open sa nepostojećom kolonom: očekivana greška")
+	}
+}
@@ -0,0 +1,205 @@
+package parquetio
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/types"
+)
+
+const (
+	readBatch = 8192 // redova po koloni u jednom čitanju
+	readNP    = 4
+)
+
+// Reader čita ravan (flat) Parquet fajl red po red kao []string, isto kao
+// csv.Reader.Read. Sa columns != nil čita samo te kolone (column pruning);
+// Header() je tada columns redosledom kako su zadate.
+type Reader struct {
+	pf     source.ParquetFile
+	pr     *reader.ParquetReader
+	header []string
+	paths  []string
+	elems  []*parquet.SchemaElement
+
+	total int64 // ukupno redova u fajlu
+	read  int64 // vraćeno redova
+	batch [][]interface{}
+	pos   int
+}
+
+// Open otvara Parquet fajl. Nepoznata kolona u columns je greška.
+func Open(path string, columns []string) (*Reader, error) {
+	pf, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := reader.NewParquetColumnReader(pf, readNP)
+	if err != nil {
+		_ = pf.Close()
+		return nil, fmt.Errorf("parquet %s: %w", path, err)
+	}
+	r := &Reader{pf: pf, pr: pr, total: pr.GetNumRows()}
+
+	sch := pr.Footer.Schema
+	if len(sch) == 0 {
+		r.Close()
+		return nil, fmt.Errorf("parquet %s: empty schema", path)
+	}
+	// reader menja Footer.Schema imena u interna (Host_ip); prava imena
+	// kolona su ExName
+	infos := pr.SchemaHandler.Infos
+	root := pr.SchemaHandler.GetRootExName()
+	byName := make(map[string]*parquet.SchemaElement, len(sch)-1)
+	var all []string
+	for i, el := range sch[1:] {
+		name := infos[i+1].ExName
+		if el.GetNumChildren() > 0 || el.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			r.Close()
+			return nil, fmt.Errorf("parquet %s: nested/repeated column %q not supported", path, name)
+		}
+		byName[name] = el
+		all = append(all, name)
+	}
+	if columns == nil {
+		columns = all
+	}
+	for _, c := range columns {
+		el, ok := byName[c]
+		if !ok {
+			r.Close()
+			return nil, fmt.Errorf("parquet %s: no column %q", path, c)
+		}
+		r.header = append(r.header, c)
+		r.paths = append(r.paths, root+common.PAR_GO_PATH_DELIMITER+c)
+		r.elems = append(r.elems, el)
+	}
+	return r, nil
+}
+
+// Columns vraća imena svih kolona fajla bez čitanja podataka.
+func Columns(path string) ([]string, error) {
+	r, err := Open(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.Header(), nil
+}
+
+func (r *Reader) Header() []string { return r.header }
+
+// Read vraća sledeći red (novi slice) ili io.EOF.
+func (r *Reader) Read() ([]string, error) {
+	if r.read >= r.total {
+		return nil, io.EOF
+	}
+	if len(r.paths) > 0 && (r.batch == nil || r.pos >= len(r.batch[0])) {
+		if err := r.fill(); err != nil {
+			// ostatak fajla je nečitljiv: greška ide pozivaocu (csvin je ne
+			// preskače kao loš red), sledeći Read vraća io.EOF
+			err = fmt.Errorf("%w (%d rows unread)", err, r.total-r.read)
+			r.read = r.total
+			return nil, err
+		}
+	}
+	rec := make([]string, len(r.paths))
+	for i := range r.paths {
+		rec[i] = format(r.batch[i][r.pos], r.elems[i])
+	}
+	r.pos++
+	r.read++
+	return rec, nil
+}
+
+func (r *Reader) fill() error {
+	n := r.total - r.read
+	if n > readBatch {
+		n = readBatch
+	}
+	if r.batch == nil {
+		r.batch = make([][]interface{}, len(r.paths))
+	}
+	for i, p := range r.paths {
+		vals, _, _, err := r.pr.ReadColumnByPath(p, n)
+		if err != nil {
+			return fmt.Errorf("parquet column %s: %w", r.header[i], err)
+		}
+		if int64(len(vals)) != n {
+			return fmt.Errorf("parquet column %s: got %d values, want %d", r.header[i], len(vals), n)
+		}
+		r.batch[i] = vals
+	}
+	r.pos = 0
+	return nil
+}
+
+func (r *Reader) Close() error {
+	r.pr.ReadStop()
+	return r.pf.Close()
+}
+
+// format: Parquet vrednost → string kakav bi bio u CSV-u.
+func format(v interface{}, el *parquet.SchemaElement) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if el.GetType() == parquet.Type_INT96 {
+			return types.INT96ToTime(t).UTC().Format(time.RFC3339Nano)
+		}
+		return t
+	case bool:
+		if t {
+			return "1"
+		}
+		return "0"
+	case int32:
+		if el.IsSetConvertedType() && el.GetConvertedType() == parquet.ConvertedType_DATE {
+			return time.Unix(int64(t)*86400, 0).UTC().Format("2006-01-02")
+		}
+		return strconv.FormatInt(int64(t), 10)
+	case int64:
+		if ts, ok := timestamp(t, el); ok {
+			return ts.UTC().Format(time.RFC3339Nano)
+		}
+		return strconv.FormatInt(t, 10)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []byte:
+		return string(t)
+	}
+	return fmt.Sprint(v)
+}
+
+func timestamp(n int64, el *parquet.SchemaElement) (time.Time, bool) {
+	if lt := el.GetLogicalType(); lt != nil && lt.IsSetTIMESTAMP() {
+		u := lt.GetTIMESTAMP().GetUnit()
+		switch {
+		case u.IsSetMILLIS():
+			return time.UnixMilli(n), true
+		case u.IsSetMICROS():
+			return time.UnixMicro(n), true
+		case u.IsSetNANOS():
+			return time.Unix(0, n), true
+		}
+	}
+	if el.IsSetConvertedType() {
+		switch el.GetConvertedType() {
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return time.UnixMilli(n), true
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			return time.UnixMicro(n), true
+		}
+	}
+	return time.Time{}, false
+}
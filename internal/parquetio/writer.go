@@ -0,0 +1,135 @@
+// Package parquetio: Parquet izlaz/ulaz za schema redove (sibling csvout/csvin).
+//
+// Tipovi kolona dolaze iz schema.Column.Kind:
+//
+//	String  → BYTE_ARRAY (UTF8)
+//	Int     → INT64
+//	Bool    → BOOLEAN
+//	TimeISO → INT64 (TIMESTAMP_MICROS, UTC)
+//
+// Sve kolone su OPTIONAL: prazna CSV vrednost je NULL, pa čitanje nazad
+// daje isti red ("" za NULL).
+package parquetio
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"parser/internal/schema"
+)
+
+const (
+	rowGroupSize = 64 << 20 // bajtova po row group-u (u memoriji pre upisa)
+	pageSize     = 64 << 10
+	writeNP      = 4 // paralelno kodiranje kolona
+)
+
+type Writer struct {
+	pw    *writer.CSVWriter
+	names []string
+	kinds []schema.Kind
+
+	coerced map[string]int64 // vrednosti koje ne odgovaraju Kind-u → NULL
+	closed  bool
+}
+
+// NewWriter piše Parquet u w (sekvencijalno; footer ide na Close).
+func NewWriter(w io.Writer, header []string) (*Writer, error) {
+	md := make([]string, len(header))
+	kinds := make([]schema.Kind, len(header))
+	for i, h := range header {
+		if strings.ContainsAny(h, ",=") {
+			return nil, fmt.Errorf("parquet: column name %q contains ',' or '='", h)
+		}
+		kinds[i] = schema.KindOf(h)
+		md[i] = "name=" + h + ", " + columnType(kinds[i]) + ", repetitiontype=OPTIONAL"
+	}
+	pw, err := writer.NewCSVWriterFromWriter(md, w, writeNP)
+	if err != nil {
+		return nil, fmt.Errorf("parquet: %w", err)
+	}
+	pw.RowGroupSize = rowGroupSize
+	pw.PageSize = pageSize
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	return &Writer{
+		pw:      pw,
+		names:   append([]string(nil), header...),
+		kinds:   kinds,
+		coerced: make(map[string]int64),
+	}, nil
+}
+
+func columnType(k schema.Kind) string {
+	switch k {
+	case schema.Int:
+		return "type=INT64"
+	case schema.Bool:
+		return "type=BOOLEAN"
+	case schema.TimeISO:
+		return "type=INT64, convertedtype=TIMESTAMP_MICROS"
+	}
+	return "type=BYTE_ARRAY, convertedtype=UTF8"
+}
+
+// WriteRow: red u header redosledu (kao csvout.Writer.WriteRow).
+// rec se kopira u novi red, pa ga pozivalac sme reciklirati (csvin.Next).
+func (w *Writer) WriteRow(rec []string) error {
+	row := make([]interface{}, len(w.names))
+	for i := range row {
+		v := ""
+		if i < len(rec) {
+			v = rec[i]
+		}
+		row[i] = w.value(i, v)
+	}
+	return w.pw.Write(row)
+}
+
+func (w *Writer) value(i int, v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	switch w.kinds[i] {
+	case schema.Int:
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return n
+		}
+	case schema.Bool:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "1", "true":
+			return true
+		case "0", "false":
+			return false
+		}
+	case schema.TimeISO:
+		if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(v)); err == nil {
+			return t.UnixMicro()
+		}
+	default:
+		return v
+	}
+	w.coerced[w.names[i]]++
+	return nil
+}
+
+// Close upisuje poslednji row group i footer. Ne zatvara ispod-ležeći writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	for name, n := range w.coerced {
+		log.Printf("[WARN] parquet: column %s: %d values not a valid %s, written as NULL (use --strict)", name, n, schema.KindOf(name))
+	}
+	if err := w.pw.WriteStop(); err != nil {
+		return fmt.Errorf("parquet: %w", err)
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package schema
 
+import "strings"
+
 type Kind int
 
 const (
@@ -31,8 +33,17 @@ var BaseColumns = []Column{
 	{Name: "source", Kind: String},
 	{Name: "target", Kind: String},
 	{Name: "botName", Kind: String},
-	{Name: "verified", Kind: String},
+	{Name: "verified", Kind: Bool},
 	{Name: "datetime", Kind: TimeISO},
+	{Name: "verified_host", Kind: String}, // FCrDNS potvrđen PTR (verify/merge)
+	{Name: "verify_reason", Kind: String}, // verifier.Reason* kod (verify/merge)
+	{Name: "verify_method", Kind: String}, // "fcrdns" ili "cidr" (verify/merge)
+	{Name: "spoofed", Kind: Bool},         // UA tvrdi poznatog bota, a IP ne pripada njemu (merge); prazno = nema presude
+	{Name: "country", Kind: String},       // ClientCountry (ISO 3166-1 alpha-2, veliko slovo)
+	{Name: "edge_colo", Kind: String},     // EdgeColoCode (IATA kod data centra)
+	{Name: "cache_status", Kind: String},  // CacheCacheStatus (hit, miss, dynamic …)
+	{Name: "ttfb_ms", Kind: Int},          // EdgeTimeToFirstByteMs
+	{Name: "origin_time_ms", Kind: Int},   // OriginResponseTime (ns) → ms
 }
 
 func BaseHeader() []string {
@@ -42,3 +53,36 @@ func BaseHeader() []string {
 	}
 	return out
 }
+
+// IsBase: da li je kolona deo BaseColumns.
+func IsBase(name string) bool {
+	for _, c := range BaseColumns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtraColumns vraća kolone iz header-a koje nisu u BaseColumns (redosled
+// iz header-a). Faze ih prenose iza BaseColumns (npr. dodatne kolone iz
+// normalize --map-spec). Poređenje je case-insensitive kao csvin.Reader.Col:
+// "User_Agent" u ulazu puni user_agent, ne postaje dodatna kolona.
+func ExtraColumns(header []string) []string {
+	var out []string
+	for _, h := range header {
+		if h = strings.TrimSpace(h); h != "" && !isBaseFold(h) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func isBaseFold(name string) bool {
+	for _, c := range BaseColumns {
+		if strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode: šta Validator radi sa vrednošću koja ne odgovara Kind-u kolone.
+type Mode int
+
+const (
+	ModeOff    Mode = iota // bez provere
+	ModeCoerce             // nevalidna vrednost → ""
+	ModeReject             // ceo red ide u rejects fajl, ne u izlaz
+	ModeFail               // prva nevalidna vrednost prekida fazu
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "off", "none":
+		return ModeOff, nil
+	case "coerce":
+		return ModeCoerce, nil
+	case "reject":
+		return ModeReject, nil
+	case "fail":
+		return ModeFail, nil
+	}
+	return ModeOff, fmt.Errorf("unknown strict mode %q (off | coerce | reject | fail)", s)
+}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeCoerce:
+		return "coerce"
+	case ModeReject:
+		return "reject"
+	case ModeFail:
+		return "fail"
+	}
+	return "off"
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Int:
+		return "int"
+	case Bool:
+		return "bool"
+	case TimeISO:
+		return "time"
+	}
+	return "string"
+}
+
+// Valid: da li v odgovara Kind-u. Prazna vrednost je uvek validna
+// (kolona nije popunjena, npr. ttfb_ms za izvor koji ga nema).
+func (k Kind) Valid(v string) bool {
+	if v == "" {
+		return true
+	}
+	switch k {
+	case Int:
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err == nil
+	case Bool:
+		switch v {
+		case "0", "1", "true", "false":
+			return true
+		}
+		return false
+	case TimeISO:
+		_, err := time.Parse(time.RFC3339Nano, v)
+		return err == nil
+	}
+	return true
+}
+
+// KindOf: Kind kolone iz BaseColumns; ostale kolone su String.
+func KindOf(name string) Kind {
+	for _, c := range BaseColumns {
+		if c.Name == name {
+			return c.Kind
+		}
+	}
+	return String
+}
+
+// RowError: prva nevalidna vrednost u redu (ModeFail).
+type RowError struct {
+	Row    int64
+	Column string
+	Kind   Kind
+	Value  string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: column %s: %q is not a valid %s", e.Row, e.Column, e.Value, e.Kind)
+}
+
+// Validator proverava redove jednog header-a. Nije bezbedan za
+// konkurentnu upotrebu (jedan po writer-u).
+type Validator struct {
+	mode  Mode
+	names []string
+	kinds []Kind
+	check []int // indeksi kolona koje nisu String
+
+	rows       int64
+	rejected   int64
+	violations []int64 // po koloni (indeks iz header-a)
+}
+
+func NewValidator(header []string, mode Mode) *Validator {
+	v := &Validator{
+		mode:       mode,
+		names:      append([]string(nil), header...),
+		kinds:      make([]Kind, len(header)),
+		violations: make([]int64, len(header)),
+	}
+	for i, h := range header {
+		v.kinds[i] = KindOf(h)
+		if v.kinds[i] != String {
+			v.check = append(v.check, i)
+		}
+	}
+	return v
+}
+
+func (v *Validator) Mode() Mode { return v.mode }
+
+// Apply proverava red i (za ModeCoerce) ga menja na mestu.
+// keep=false znači da red treba odbaciti (ModeReject); err != nil je
+// *RowError za ModeFail.
+func (v *Validator) Apply(row []string) (keep bool, err error) {
+	if v.mode == ModeOff {
+		return true, nil
+	}
+	v.rows++
+	keep = true
+	for _, i := range v.check {
+		if i >= len(row) || v.kinds[i].Valid(row[i]) {
+			continue
+		}
+		v.violations[i]++
+		switch v.mode {
+		case ModeCoerce:
+			row[i] = ""
+		case ModeReject:
+			keep = false
+		case ModeFail:
+			return false, &RowError{Row: v.rows, Column: v.names[i], Kind: v.kinds[i], Value: row[i]}
+		}
+	}
+	if !keep {
+		v.rejected++
+	}
+	return keep, nil
+}
+
+// Rejected: broj odbačenih redova (ModeReject).
+func (v *Validator) Rejected() int64 { return v.rejected }
+
+// Violations: broj nevalidnih vrednosti po koloni (samo kolone sa > 0).
+func (v *Validator) Violations() map[string]int64 {
+	out := make(map[string]int64)
+	for i, n := range v.violations {
+		if n > 0 {
+			out[v.names[i]] += n
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKindValid(t *testing.T) {
+	cases := []struct {
+		kind Kind
+		v    string
+		ok   bool
+	}{
+		{Int, "", true},
+		{Int, "200", true},
+		{Int, "-5", true},
+		{Int, "1.5", false},
+		{Int, "abc", false},
+		{Bool, "1", true},
+		{Bool, "false", true},
+		{Bool, "yes", false},
+		{TimeISO, "2025-09-01T10:00:00Z", true},
+		{TimeISO, "2025-09-01T10:00:00.123456789+02:00", true},
+		{TimeISO, "2025-09-01 10:00:00", false},
+		{TimeISO, "1756720800", false},
+		{String, "bilo šta", true},
+	}
+	for _, tc := range cases {
+		if got := tc.kind.Valid(tc.v); got != tc.ok {
+			t.Errorf("%s.Valid(%q) = %v, want %v", tc.kind, tc.v, got, tc.ok)
+		}
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	cases := map[string]Kind{
+		"verified":    Bool,
+		"spoofed":     Bool,
+		"status_code": Int,
+		"datetime":    TimeISO,
+		"time_zone":   String,
+		"nepoznata":   String,
+	}
+	for col, want := range cases {
+		if got := KindOf(col); got != want {
+			t.Errorf("KindOf(%q) = %s, want %s", col, got, want)
+		}
+	}
+}
+
+func TestValidatorModes(t *testing.T) {
+	header := []string{"host_ip", "status_code", "datetime", "spoofed", "extra"}
+	rows := func() [][]string {
+		return [][]string{
+			{"1.2.3.4", "200", "2025-09-01T10:00:00Z", "0", "x"},
+			{"1.2.3.4", "abc", "1756720800", "1", "x"},
+			{"1.2.3.4", "", "", "maybe", "x"},
+			{"1.2.3.4", "200"}, // kraći red: nedostajuće kolone se ne proveravaju
+		}
+	}
+
+	t.Run("coerce", func(t *testing.T) {
+		v := NewValidator(header, ModeCoerce)
+		rs := rows()
+		for _, r := range rs {
+			if keep, err := v.Apply(r); !keep || err != nil {
+				t.Fatalf("Apply(%v) = (%v, %v)", r, keep, err)
+			}
+		}
+		if want := []string{"1.2.3.4", "", "", "1", "x"}; !reflect.DeepEqual(rs[1], want) {
+			t.Errorf("row = %v, want %v", rs[1], want)
+		}
+		if want := map[string]int64{"status_code": 1, "datetime": 1, "spoofed": 1}; !reflect.DeepEqual(v.Violations(), want) {
+			t.Errorf("Violations = %v, want %v", v.Violations(), want)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		v := NewValidator(header, ModeReject)
+		var kept []bool
+		for _, r := range rows() {
+			keep, err := v.Apply(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kept = append(kept, keep)
+		}
+		if want := []bool{true, false, false, true}; !reflect.DeepEqual(kept, want) {
+			t.Errorf("keep = %v, want %v", kept, want)
+		}
+		if v.Rejected() != 2 {
+			t.Errorf("Rejected = %d, want 2", v.Rejected())
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		v := NewValidator(header, ModeFail)
+		rs := rows()
+		if _, err := v.Apply(rs[0]); err != nil {
+			t.Fatal(err)
+		}
+		_, err := v.Apply(rs[1])
+		var re *RowError
+		if !errors.As(err, &re) {
+			t.Fatalf("err = %v, want *RowError", err)
+		}
+		if re.Row != 2 || re.Column != "status_code" || re.Value != "abc" {
+			t.Errorf("RowError = %+v", re)
+		}
+	})
+
+	t.Run("off", func(t *testing.T) {
+		v := NewValidator(header, ModeOff)
+		r := rows()[1]
+		if keep, err := v.Apply(r); !keep || err != nil || r[1] != "abc" {
+			t.Errorf("ModeOff menja red: keep=%v err=%v row=%v", keep, err, r)
+		}
+	})
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"off", "coerce", "reject", "fail"} {
+		m, err := ParseMode(s)
+		if err != nil || m.String() != s {
+			t.Errorf("ParseMode(%q) = (%v, %v)", s, m, err)
+		}
+	}
+	if _, err := ParseMode("strict"); err == nil {
+		t.Error("ParseMode(\"strict\"): očekivana greška")
+	}
+}
@@ -0,0 +1,82 @@
+package source
+
+// Cloudflare Logpush: polja su već kanonska, profil je identitet.
+var Cloudflare = &Profile{
+	Name:   "cloudflare",
+	Detect: []string{"ClientIP", "EdgeEndTimestamp"},
+}
+
+// CloudFront standardni logovi (W3C, tab-separated, "#Fields:" header).
+// UA i referer su URL-enkodovani; cs(Host) je CloudFront domen, pravi
+// host je u x-host-header.
+var CloudFront = &Profile{
+	Name:   "cloudfront",
+	Detect: []string{"c-ip", "cs-uri-stem"},
+	Fields: map[string]Field{
+		"EdgeEndTimestamp":       {From: []string{"date"}, Join: []string{"time"}, Time: "2006-01-02 15:04:05"},
+		"ClientIP":               {From: []string{"c-ip"}},
+		"ClientRequestMethod":    {From: []string{"cs-method"}},
+		"ClientRequestHost":      {From: []string{"x-host-header", "cs(Host)"}},
+		"ClientRequestPath":      {From: []string{"cs-uri-stem"}},
+		"ClientRequestURI":       {From: []string{"cs-uri-stem"}, Query: "cs-uri-query"},
+		"ClientRequestScheme":    {From: []string{"cs-protocol"}, Lower: true},
+		"EdgeResponseStatus":     {From: []string{"sc-status"}},
+		"EdgeResponseBytes":      {From: []string{"sc-bytes"}},
+		"ClientRequestReferer":   {From: []string{"cs(Referer)"}, Unescape: true},
+		"ClientRequestUserAgent": {From: []string{"cs(User-Agent)"}, Unescape: true},
+		"ClientCountry":          {From: []string{"c-country"}},
+		"EdgeColoCode":           {From: []string{"x-edge-location"}},
+		"CacheCacheStatus":       {From: []string{"x-edge-result-type"}, Lower: true},
+		"EdgeTimeToFirstByteMs":  {From: []string{"time-to-first-byte"}, Scale: 1000}, // sekunde
+	},
+}
+
+// Akamai DataStream 2 (JSON). reqTimeSec je unix vreme sa milisekundama;
+// putanja, UA i referer su URL-enkodovani.
+var Akamai = &Profile{
+	Name:   "akamai",
+	Detect: []string{"cliIP", "reqTimeSec"},
+	Fields: map[string]Field{
+		"EdgeEndTimestamp":       {From: []string{"reqTimeSec"}, Time: "unix"},
+		"ClientIP":               {From: []string{"cliIP"}},
+		"ClientRequestMethod":    {From: []string{"reqMethod"}},
+		"ClientRequestHost":      {From: []string{"reqHost"}},
+		"ClientRequestPath":      {From: []string{"reqPath"}, Unescape: true},
+		"ClientRequestURI":       {From: []string{"reqPath"}, Query: "queryStr", Unescape: true},
+		"ClientRequestScheme":    {From: []string{"scheme", "proto"}, Lower: true},
+		"EdgeResponseStatus":     {From: []string{"statusCode"}},
+		"EdgeResponseBytes":      {From: []string{"bytes", "rspContentLen", "totalBytes"}},
+		"ClientRequestReferer":   {From: []string{"referer"}, Unescape: true},
+		"ClientRequestUserAgent": {From: []string{"UA"}, Unescape: true},
+		"ClientCountry":          {From: []string{"country"}},
+		"CacheCacheStatus":       {From: []string{"cacheStatus"}, Map: map[string]string{"0": "miss", "1": "hit"}},
+		"EdgeTimeToFirstByteMs":  {From: []string{"timeToFirstByte"}},
+	},
+}
+
+// Fastly JSON (preporučeni log_format iz Fastly dokumentacije:
+// timestamp, client_ip, host, url, request_method, response_status …).
+var Fastly = &Profile{
+	Name:   "fastly",
+	Detect: []string{"client_ip", "request_user_agent"},
+	Fields: map[string]Field{
+		"EdgeEndTimestamp":       {From: []string{"timestamp", "time_start"}, Time: "auto"},
+		"ClientIP":               {From: []string{"client_ip"}},
+		"ClientRequestMethod":    {From: []string{"request_method"}},
+		"ClientRequestHost":      {From: []string{"host"}},
+		"ClientRequestPath":      {From: []string{"url"}, Path: true},
+		"ClientRequestURI":       {From: []string{"url"}},
+		"ClientRequestScheme":    {From: []string{"scheme", "request_scheme"}, Lower: true},
+		"EdgeResponseStatus":     {From: []string{"response_status"}},
+		"EdgeResponseBytes":      {From: []string{"response_body_size", "response_bytes"}},
+		"ClientRequestReferer":   {From: []string{"request_referer"}},
+		"ClientRequestUserAgent": {From: []string{"request_user_agent"}},
+		"ClientCountry":          {From: []string{"geo_country_code", "geo_country"}},
+		"EdgeColoCode":           {From: []string{"pop", "server_datacenter"}},
+		"CacheCacheStatus":       {From: []string{"cache_status", "fastly_info_state"}, Lower: true},
+		"EdgeTimeToFirstByteMs":  {From: []string{"time_to_first_byte"}},
+	},
+}
+
+// builtins: redosled detekcije.
+var builtins = []*Profile{Cloudflare, CloudFront, Akamai, Fastly}
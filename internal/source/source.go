@@ -0,0 +1,385 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile opisuje jedan format izvornog loga: kako se njegova polja
+// preslikavaju na kanonska (Cloudflare) imena koja mapper.MapToCSV čita
+// (ClientIP, EdgeEndTimestamp, ClientRequestURI …). Tako svaki izvor daje
+// isti schema.BaseColumns red.
+//
+// Korisnički profil (YAML ili JSON):
+//
+//	name: mycdn
+//	detect: [ts, client]            # kolone po kojima se format prepoznaje
+//	fields:
+//	  EdgeEndTimestamp: {from: [ts], time: unix_ms}
+//	  ClientIP:         {from: [client, ip]}
+//	  ClientRequestURI: {from: [path], query: qs, unescape: true}
+//	  EdgeTimeToFirstByteMs: {from: [ttfb_s], scale: 1000}
+type Profile struct {
+	Name   string           `json:"name" yaml:"name"`
+	Detect []string         `json:"detect" yaml:"detect"`
+	Fields map[string]Field `json:"fields" yaml:"fields"`
+}
+
+// Field je pravilo za jedno kanonsko polje.
+type Field struct {
+	From     []string          `json:"from" yaml:"from"`                             // izvorna polja; prvo neprazno pobeđuje ("-" je prazno)
+	Join     []string          `json:"join,omitempty" yaml:"join,omitempty"`         // polja koja se dodaju sa razmakom (npr. date + time)
+	Query    string            `json:"query,omitempty" yaml:"query,omitempty"`       // polje sa query stringom; dodaje se kao "?q"
+	Time     string            `json:"time,omitempty" yaml:"time,omitempty"`         // auto | rfc3339 | unix | unix_ms | unix_ns | Go layout → RFC3339 UTC
+	Unescape bool              `json:"unescape,omitempty" yaml:"unescape,omitempty"` // URL-decode (%20 → ' ')
+	Path     bool              `json:"path,omitempty" yaml:"path,omitempty"`         // odseci "?query" (URL → path)
+	Lower    bool              `json:"lower,omitempty" yaml:"lower,omitempty"`
+	Default  string            `json:"default,omitempty" yaml:"default,omitempty"` // kad su sva From polja prazna
+	Map      map[string]string `json:"map,omitempty" yaml:"map,omitempty"`         // zamena vrednosti (npr. Akamai cacheStatus 1 → hit)
+	Scale    float64           `json:"scale,omitempty" yaml:"scale,omitempty"`     // množilac za brojeve (npr. 1000 za s → ms)
+}
+
+// Canonical preslikava red izvora na kanonska imena. Profil bez Fields je
+// identitet (ulaz je već u Cloudflare imenima).
+func (p *Profile) Canonical(src map[string]string) map[string]string {
+	if len(p.Fields) == 0 {
+		return src
+	}
+	out := make(map[string]string, len(p.Fields))
+	get := func(k string) string { return src[k] }
+	for name, f := range p.Fields {
+		out[name] = f.value(get)
+	}
+	return out
+}
+
+// Binding je Canonical za jedan header bez mapa: imena se razrešavaju
+// jednom (Bind), a Values puni kanonska polja direktno iz CSV reda.
+type Binding struct {
+	fields []string
+	direct []int    // profil bez Fields: indeks polja u header-u (-1 nema)
+	rules  []*Field // inače: pravilo po polju (nil → "")
+	index  map[string]int
+}
+
+// Bind priprema Binding za ulaz sa datim header-om; fields su kanonska
+// polja koja se traže (npr. mapper.SourceFields()), istim redosledom kao
+// rezultat Values.
+func (p *Profile) Bind(header, fields []string) *Binding {
+	b := &Binding{fields: fields, index: make(map[string]int, len(header))}
+	for i, h := range header {
+		b.index[strings.TrimSpace(h)] = i
+	}
+	if len(p.Fields) == 0 {
+		b.direct = make([]int, len(fields))
+		for i, f := range fields {
+			if idx, ok := b.index[f]; ok {
+				b.direct[i] = idx
+			} else {
+				b.direct[i] = -1
+			}
+		}
+		return b
+	}
+	b.rules = make([]*Field, len(fields))
+	for i, name := range fields {
+		if f, ok := p.Fields[name]; ok {
+			b.rules[i] = &f
+		}
+	}
+	return b
+}
+
+// Values upisuje kanonske vrednosti reda rec u vals (len(vals) mora biti
+// len(fields)) i vraća vals. Isti rezultat kao Canonical nad mapom reda.
+func (b *Binding) Values(rec, vals []string) []string {
+	if b.direct != nil {
+		for i, idx := range b.direct {
+			vals[i] = at(rec, idx)
+		}
+		return vals
+	}
+	get := func(k string) string {
+		if idx, ok := b.index[k]; ok {
+			return at(rec, idx)
+		}
+		return ""
+	}
+	for i, f := range b.rules {
+		if f == nil {
+			vals[i] = ""
+			continue
+		}
+		vals[i] = f.value(get)
+	}
+	return vals
+}
+
+func at(rec []string, i int) string {
+	if i >= 0 && i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+// SourceFields vraća izvorna polja koja profil čita (za jsonl --jsonl-columns).
+// Za identitet (Cloudflare) vraća nil — ta lista je mapper.SourceFields.
+func (p *Profile) SourceFields() []string {
+	if len(p.Fields) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var out []string
+	add := func(k string) {
+		if k == "" {
+			return
+		}
+		if _, ok := seen[k]; !ok {
+			seen[k] = struct{}{}
+			out = append(out, k)
+		}
+	}
+	for _, f := range p.Fields {
+		for _, k := range f.From {
+			add(k)
+		}
+		for _, k := range f.Join {
+			add(k)
+		}
+		add(f.Query)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// clean: "-" i prazno su isto (nema vrednosti).
+func clean(get func(string) string, k string) string {
+	v := strings.TrimSpace(get(k))
+	if v == "-" {
+		return ""
+	}
+	return v
+}
+
+func (f Field) value(get func(string) string) string {
+	v := ""
+	for _, k := range f.From {
+		if v = clean(get, k); v != "" {
+			break
+		}
+	}
+	if v == "" {
+		v = f.Default
+	}
+	for _, k := range f.Join {
+		if jv := clean(get, k); jv != "" {
+			v += " " + jv
+		}
+	}
+	if f.Unescape {
+		if u, err := url.PathUnescape(v); err == nil {
+			v = u
+		}
+	}
+	if f.Path {
+		if i := strings.IndexByte(v, '?'); i >= 0 {
+			v = v[:i]
+		}
+	}
+	if f.Query != "" {
+		if q := strings.TrimPrefix(clean(get, f.Query), "?"); q != "" && v != "" {
+			v += "?" + q
+		}
+	}
+	if f.Lower {
+		v = strings.ToLower(v)
+	}
+	if r, ok := f.Map[v]; ok {
+		v = r
+	}
+	if f.Scale != 0 && v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			v = strconv.FormatInt(int64(math.Round(n*f.Scale)), 10)
+		}
+	}
+	if f.Time != "" && v != "" {
+		if t, ok := ParseTime(v, f.Time); ok {
+			v = t.UTC().Format(time.RFC3339)
+		}
+	}
+	return v
+}
+
+// layouti koje "auto" pokušava (posle RFC3339 i unix brojeva)
+var autoLayouts = []string{
+	"2006-01-02T15:04:05-0700", // Fastly strftime %Y-%m-%dT%H:%M:%S%z
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+}
+
+// ParseTime parsira v po spec-u: auto | rfc3339 | unix | unix_ms | unix_ns |
+// Go layout. Unix vrednosti se vraćaju u UTC; "auto" bira sekunde,
+// milisekunde ili nanosekunde po veličini broja (Logpush unix/unixnano).
+func ParseTime(v, spec string) (time.Time, bool) {
+	switch strings.ToLower(spec) {
+	case "rfc3339":
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	case "unix", "unix_ms", "unix_ns":
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		switch strings.ToLower(spec) {
+		case "unix_ms":
+			return time.UnixMilli(int64(f)).UTC(), true
+		case "unix_ns":
+			n, err := strconv.ParseInt(v, 10, 64)
+			return time.Unix(0, n).UTC(), err == nil
+		}
+		return unixSeconds(f), true
+	case "auto":
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			// sekunde / milisekunde / nanosekunde po veličini
+			switch {
+			case f > 1e17:
+				if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+					return time.Unix(0, n).UTC(), true // bez float zaokruživanja
+				}
+				return time.Unix(0, int64(f)).UTC(), true
+			case f > 1e11:
+				return time.UnixMilli(int64(f)).UTC(), true
+			}
+			return unixSeconds(f), true
+		}
+		for _, l := range autoLayouts {
+			if t, err := time.Parse(l, v); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	default:
+		t, err := time.Parse(spec, v)
+		return t, err == nil
+	}
+}
+
+func unixSeconds(f float64) time.Time {
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC()
+}
+
+// matches: header sadrži sve Detect kolone.
+func (p *Profile) matches(header []string) bool {
+	if len(p.Detect) == 0 {
+		return false
+	}
+	have := make(map[string]struct{}, len(header))
+	for _, h := range header {
+		have[strings.TrimSpace(h)] = struct{}{}
+	}
+	for _, d := range p.Detect {
+		if _, ok := have[d]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Detect bira ugrađeni profil po header-u ulaza.
+func Detect(header []string) (*Profile, bool) {
+	for _, p := range builtins {
+		if p.matches(header) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup vraća ugrađeni profil po imenu.
+func Lookup(name string) (*Profile, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, p := range builtins {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Names vraća imena ugrađenih profila (za poruke i --help).
+func Names() []string {
+	out := make([]string, len(builtins))
+	for i, p := range builtins {
+		out[i] = p.Name
+	}
+	return out
+}
+
+// Resolve tumači --source-format: "auto"/"" (detekcija po header-u),
+// ime ugrađenog profila ili putanja do .yaml/.yml/.json profila.
+// Za "auto" bez poklapanja vraća Cloudflare i detected=false.
+func Resolve(format string, header []string) (p *Profile, detected bool, err error) {
+	f := strings.TrimSpace(format)
+	switch strings.ToLower(f) {
+	case "", "auto":
+		if p, ok := Detect(header); ok {
+			return p, true, nil
+		}
+		return Cloudflare, false, nil
+	}
+	if p, ok := Lookup(f); ok {
+		return p, false, nil
+	}
+	switch strings.ToLower(filepath.Ext(f)) {
+	case ".yaml", ".yml", ".json":
+		p, err := LoadFile(f)
+		return p, false, err
+	}
+	return nil, false, fmt.Errorf("source: unknown format %q (use auto, %s or a .yaml/.json profile)", format, strings.Join(Names(), ", "))
+}
+
+// LoadFile učitava korisnički profil (YAML ili JSON).
+func LoadFile(path string) (*Profile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Profile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, &p)
+	default:
+		err = yaml.Unmarshal(b, &p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("source profile %q: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("source profile %q: %w", path, errors.New("no fields"))
+	}
+	for name, f := range p.Fields {
+		if len(f.From) == 0 && f.Default == "" {
+			return nil, fmt.Errorf("source profile %q: field %q has neither from nor default", path, name)
+		}
+	}
+	return &p, nil
+}
@@ -0,0 +1,91 @@
+package source
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// IsW3C proverava da li ulaz počinje W3C extended log direktivom
+// ("#Version:" ili "#Fields:"), kao CloudFront standardni logovi.
+func IsW3C(br *bufio.Reader) bool {
+	b, _ := br.Peek(16)
+	s := string(b)
+	return strings.HasPrefix(s, "#Version:") || strings.HasPrefix(s, "#Fields:")
+}
+
+// W3CReader čita W3C extended log: header iz "#Fields:" linije, redovi su
+// tab-separated, ostale "#" linije se preskaču. API prati csvin.Reader.
+type W3CReader struct {
+	br     *bufio.Reader
+	header []string
+	inited bool
+}
+
+func NewW3CReader(br *bufio.Reader) *W3CReader {
+	return &W3CReader{br: br}
+}
+
+func (r *W3CReader) init() error {
+	if r.inited {
+		return nil
+	}
+	for {
+		line, err := r.br.ReadString('\n')
+		if strings.HasPrefix(line, "#Fields:") {
+			r.header = strings.Fields(strings.TrimPrefix(line, "#Fields:"))
+			r.inited = true
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("w3c: no #Fields header")
+			}
+			return err
+		}
+		if !strings.HasPrefix(line, "#") {
+			return errors.New("w3c: data before #Fields header")
+		}
+	}
+}
+
+func (r *W3CReader) Header() ([]string, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.header, nil
+}
+
+func (r *W3CReader) Next() (map[string]string, error) {
+	vals, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]string, len(r.header))
+	for i, name := range r.header {
+		if i < len(vals) {
+			row[name] = vals[i]
+		} else {
+			row[name] = ""
+		}
+	}
+	return row, nil
+}
+
+// Read vraća sledeći red kao polja u Header() redosledu (bez mape).
+func (r *W3CReader) Read() ([]string, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	for {
+		line, err := r.br.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return strings.Split(line, "\t"), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
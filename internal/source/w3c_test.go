@@ -0,0 +1,60 @@
+package source
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const cloudfrontLog = "#Version: 1.0\n" +
+	"#Fields: date time c-ip cs-method cs-uri-stem sc-status\n" +
+	"2025-09-01\t10:00:00\t66.249.66.1\tGET\t/p\t200\n" +
+	"# komentar usred fajla\n" +
+	"\n" +
+	"2025-09-01\t10:00:01\t1.2.3.4\tHEAD\r\n" // CRLF, kraći red
+
+func TestW3CReader(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader(cloudfrontLog))
+	if !IsW3C(br) {
+		t.Fatal("IsW3C = false")
+	}
+	r := NewW3CReader(br)
+	h, err := r.Header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"date", "time", "c-ip", "cs-method", "cs-uri-stem", "sc-status"}; !reflect.DeepEqual(h, want) {
+		t.Fatalf("header = %v, want %v", h, want)
+	}
+
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2025-09-01", "10:00:00", "66.249.66.1", "GET", "/p", "200"}; !reflect.DeepEqual(rec, want) {
+		t.Errorf("Read = %v, want %v", rec, want)
+	}
+	row, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row["c-ip"] != "1.2.3.4" || row["cs-method"] != "HEAD" || row["sc-status"] != "" {
+		t.Errorf("Next = %v", row)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
+
+func TestW3CReaderErrors(t *testing.T) {
+	for _, in := range []string{"#Version: 1.0\n", "2025-09-01\t10:00:00\n#Fields: date time\n"} {
+		if _, err := NewW3CReader(bufio.NewReader(strings.NewReader(in))).Header(); err == nil {
+			t.Errorf("Header(%q): očekivana greška", in)
+		}
+	}
+	if IsW3C(bufio.NewReader(strings.NewReader("ClientIP,EdgeEndTimestamp\n"))) {
+		t.Error("IsW3C(CSV) = true")
+	}
+}
@@ -0,0 +1,157 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resolver je podskup net.Resolver API-ja koji verify stage koristi.
+// *net.Resolver ga zadovoljava direktno.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// SystemResolver vraća podrazumevani sistemski resolver.
+func SystemResolver() Resolver {
+	return net.DefaultResolver
+}
+
+// NewServerResolver vraća resolver koji sve upite šalje na zadati DNS server
+// ("1.1.1.1" ili "10.0.0.53:5353"; port je 53 ako nije naveden).
+func NewServerResolver(addr string) Resolver {
+	addr = strings.TrimSpace(addr)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+	d := net.Dialer{Timeout: 5 * time.Second}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// FakeResolver odgovara iz statičkih tabela (IP→PTR i host→IP), bez mreže.
+// Namenjen CI-ju i air-gapped mašinama.
+type FakeResolver struct {
+	PTR   map[string][]string `json:"ptr" yaml:"ptr"`
+	Hosts map[string][]string `json:"hosts" yaml:"hosts"`
+	// Fail: IP adrese i hostovi za koje lookup vraća privremenu DNS grešku
+	// (SERVFAIL), da bi se offline proverile lookup_error presude.
+	Fail []string `json:"fail,omitempty" yaml:"fail,omitempty"`
+
+	fail map[string]struct{}
+}
+
+// NewFileResolver učitava FakeResolver iz JSON ili YAML fajla:
+//
+//	ptr:   { "66.249.66.1": ["crawl-66-249-66-1.googlebot.com"] }
+//	hosts: { "crawl-66-249-66-1.googlebot.com": ["66.249.66.1"] }
+//	fail:  ["203.0.113.9", "crawl-5.googlebot.com"]
+func NewFileResolver(path string) (*FakeResolver, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fr FakeResolver
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &fr); err != nil {
+			return nil, err
+		}
+	case ".json":
+		if err := json.Unmarshal(b, &fr); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported resolver file format (use .json or .yaml/.yml)")
+	}
+
+	// ključevi se porede bez završne tačke i case-insensitive za hostove
+	ptr := make(map[string][]string, len(fr.PTR))
+	for ip, names := range fr.PTR {
+		ptr[normalizeIP(ip)] = names
+	}
+	hosts := make(map[string][]string, len(fr.Hosts))
+	for h, ips := range fr.Hosts {
+		hosts[normalizeHost(h)] = ips
+	}
+	fr.PTR, fr.Hosts = ptr, hosts
+	fr.fail = make(map[string]struct{}, 2*len(fr.Fail))
+	for _, k := range fr.Fail {
+		fr.fail[normalizeIP(k)] = struct{}{}
+		fr.fail[normalizeHost(k)] = struct{}{}
+	}
+	return &fr, nil
+}
+
+func (f *FakeResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	if _, ok := f.fail[normalizeIP(addr)]; ok {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: addr, IsTemporary: true}
+	}
+	names, ok := f.PTR[normalizeIP(addr)]
+	if !ok || len(names) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+	}
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		out = append(out, strings.TrimSuffix(n, ".")+".")
+	}
+	return out, nil
+}
+
+func (f *FakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if _, ok := f.fail[normalizeHost(host)]; ok {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+	}
+	ips, ok := f.Hosts[normalizeHost(host)]
+	if !ok || len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	out := make([]net.IPAddr, 0, len(ips))
+	for _, s := range ips {
+		if ip := net.ParseIP(strings.TrimSpace(s)); ip != nil {
+			out = append(out, net.IPAddr{IP: ip})
+		}
+	}
+	return out, nil
+}
+
+// OpenResolver bira resolver po CLI flagovima: fake fajl ima prednost,
+// zatim eksplicitni DNS server, inače sistemski resolver.
+func OpenResolver(server, fakePath string) (Resolver, error) {
+	if fakePath != "" {
+		fr, err := NewFileResolver(fakePath)
+		if err != nil {
+			return nil, fmt.Errorf("load fake resolver: %w", err)
+		}
+		return fr, nil
+	}
+	if server != "" {
+		return NewServerResolver(server), nil
+	}
+	return SystemResolver(), nil
+}
+
+func normalizeIP(ip string) string {
+	ip = strings.Trim(strings.TrimSpace(ip), "[]")
+	if p := net.ParseIP(ip); p != nil {
+		return p.String()
+	}
+	return ip
+}
+
+func normalizeHost(h string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(h), "."))
+}
@@ -3,23 +3,48 @@ package verifier
 import (
 	"context"
 	"encoding/csv"
+	"errors"
+	"fmt"
 	"net"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"parser/internal/botdetector"
+	"parser/internal/iox"
+)
+
+// Reason kodovi za Result.Reason (upisuju se u verified.csv i merged_ai.csv).
+const (
+	ReasonConfirmed       = "fcrdns_ok"        // PTR poznat i forward lookup vraća isti IP
+	ReasonNoPTR           = "no_ptr"           // IP nema PTR zapis
+	ReasonUnknownPTR      = "ptr_unknown"      // PTR postoji, ali ne pripada poznatom botu
+	ReasonForwardMismatch = "forward_mismatch" // PTR poznat, ali A/AAAA ne vraća originalni IP
+	ReasonLookupError     = "lookup_error"     // DNS greška (timeout, SERVFAIL …)
+	ReasonCIDRMatch       = "cidr_match"       // IP je u zvaničnoj listi opsega bota
+)
+
+// Metode verifikacije za Result.Method.
+const (
+	MethodDNS  = "fcrdns"
+	MethodCIDR = "cidr"
 )
 
 type Result struct {
 	IP       string
-	BotName  string // bazni PTR domen (ako PTR postoji), inače labela; bez '|'
-	Verified bool   // true ako je botdetector prepoznao poznatog bota (po PTR-u)
+	BotName  string // kanonsko ime bota ako je potvrđen, inače bazni PTR domen; bez '|'
+	Verified bool   // true samo ako je PTR poznat I forward-confirmed (FCrDNS)
+	Hostname string // PTR hostname koji je potvrđen forward lookup-om ("" ako nije)
+	Reason   string // jedan od Reason* kodova
+	Method   string // MethodDNS ili MethodCIDR
 }
 
 // VerifyIPs – parallel reverse DNS lookup with progress channel.
-func VerifyIPs(ctx context.Context, ips []string, workers int, timeout time.Duration, progress chan<- int) ([]Result, error) {
+// If r is nil, the system resolver is used.
+func VerifyIPs(ctx context.Context, r Resolver, ips []string, workers int, timeout time.Duration, progress chan<- int) ([]Result, error) {
+	if r == nil {
+		r = SystemResolver()
+	}
 	jobs := make(chan string, workers*2)
 	resultsChan := make(chan Result, workers*2)
 	var wg sync.WaitGroup
@@ -31,19 +56,7 @@ func VerifyIPs(ctx context.Context, ips []string, workers int, timeout time.Dura
 			case <-ctx.Done():
 				return
 			default:
-				rCtx, cancel := context.WithTimeout(ctx, timeout)
-				ptrs, _ := net.DefaultResolver.LookupAddr(rCtx, ip)
-				cancel()
-
-				botNameCanonical, verified := canonicalFromPTRs(ptrs)
-				if botNameCanonical == "" {
-					botNameCanonical = "unable to verify bot"
-				}
-				resultsChan <- Result{
-					IP:       ip,
-					BotName:  botNameCanonical,
-					Verified: verified,
-				}
+				resultsChan <- verifyOne(ctx, r, ip, timeout)
 				if progress != nil {
 					progress <- 1
 				}
@@ -72,8 +85,130 @@ func VerifyIPs(ctx context.Context, ips []string, workers int, timeout time.Dura
 	return results, nil
 }
 
+// verifyOne radi FCrDNS za jedan IP: PTR lookup, pa forward (A/AAAA) lookup
+// svakog PTR-a koji pripada poznatom botu. Verified je true samo ako se
+// originalni IP vrati u forward odgovoru.
+func verifyOne(ctx context.Context, r Resolver, ip string, timeout time.Duration) Result {
+	if res, ok := MatchCIDR(ip); ok {
+		return res
+	}
+	res := Result{IP: ip, Method: MethodDNS}
+
+	rCtx, cancel := context.WithTimeout(ctx, timeout)
+	ptrs, err := r.LookupAddr(rCtx, ip)
+	cancel()
+
+	ptrs = cleanPTRs(ptrs)
+	if len(ptrs) == 0 {
+		res.BotName = "unable to verify bot"
+		res.Reason = ReasonNoPTR
+		if err != nil && !isNotFound(err) {
+			res.Reason = ReasonLookupError
+		}
+		return res
+	}
+
+	res.BotName = baseDomain(stripNumericPrefix(ptrs[0]))
+	res.Reason = ReasonUnknownPTR
+
+	for _, p := range ptrs {
+		bot, ok := botdetector.MatchPTR(p)
+		if !ok {
+			continue
+		}
+		ok, ferr := forwardConfirms(ctx, r, p, ip, timeout)
+		if ok {
+			// kanonsko ime iz pravila, isto kao UA labela iz normalize
+			res.BotName = bot.Name
+			res.Verified = true
+			res.Hostname = p
+			res.Reason = ReasonConfirmed
+			return res
+		}
+		if ferr != nil && !isNotFound(ferr) {
+			res.Reason = ReasonLookupError
+		} else if res.Reason != ReasonLookupError {
+			res.Reason = ReasonForwardMismatch
+		}
+	}
+	return res
+}
+
+// MatchCIDR proverava IP protiv zvaničnih CIDR lista iz bots fajla (bez mreže).
+func MatchCIDR(ip string) (Result, bool) {
+	name, ok := botdetector.MatchIP(ip)
+	if !ok {
+		return Result{}, false
+	}
+	return Result{
+		IP:       ip,
+		BotName:  name,
+		Verified: true,
+		Reason:   ReasonCIDRMatch,
+		Method:   MethodCIDR,
+	}, true
+}
+
+// SplitByCIDR deli IP adrese na one potvrđene CIDR listama i ostatak
+// koji ide na DNS proveru.
+func SplitByCIDR(ips []string) ([]Result, []string) {
+	if !botdetector.HasCIDRs() {
+		return nil, ips
+	}
+	var matched []Result
+	rest := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if res, ok := MatchCIDR(ip); ok {
+			matched = append(matched, res)
+			continue
+		}
+		rest = append(rest, ip)
+	}
+	return matched, rest
+}
+
+// forwardConfirms: da li A/AAAA lookup hosta vraća originalni IP.
+func forwardConfirms(ctx context.Context, r Resolver, host, ip string, timeout time.Duration) (bool, error) {
+	want := net.ParseIP(ip)
+	if want == nil {
+		return false, nil
+	}
+	fCtx, cancel := context.WithTimeout(ctx, timeout)
+	addrs, err := r.LookupIPAddr(fCtx, host)
+	cancel()
+	for _, a := range addrs {
+		if a.IP.Equal(want) {
+			return true, nil
+		}
+	}
+	return false, err
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
 // ---- helpers ----
 
+// cleanPTRs: trim, skini završnu tačku i duplikate (redosled ostaje).
+func cleanPTRs(ptrs []string) []string {
+	clean := make([]string, 0, len(ptrs))
+	seen := make(map[string]struct{}, len(ptrs))
+	for _, p := range ptrs {
+		p = strings.TrimSpace(strings.TrimSuffix(p, "."))
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		clean = append(clean, p)
+	}
+	return clean
+}
+
 // stripNumericPrefix: odbaci vodeće labele koje sadrže cifru.
 // "66-249-66-1.googlebot.com"         -> "googlebot.com"
 // "crawl-66-249-75-166.googlebot.com" -> "googlebot.com"
@@ -116,53 +251,23 @@ func baseDomain(host string) string {
 	return second + "." + last
 }
 
-// canonicalFromPTRs:
-// - Ako postoji bar jedan PTR: uzmi prvi PTR, skini numeričke prefikse, pa svedi na eTLD+1 (npr. "googlebot.com").
-// - Verified je true ako je bilo koji PTR match-ovan u botdetectoru (labelu NE vraćamo u botName).
-// - Ako nema PTR-ova: "", false (caller će upisati "unable to verify bot").
-func canonicalFromPTRs(ptrs []string) (string, bool) {
-	clean := make([]string, 0, len(ptrs))
-	seen := make(map[string]struct{}, len(ptrs))
-	for _, p := range ptrs {
-		p = strings.TrimSpace(strings.TrimSuffix(p, "."))
-		if p == "" {
-			continue
-		}
-		if _, ok := seen[p]; ok {
-			continue
-		}
-		seen[p] = struct{}{}
-		clean = append(clean, p)
-	}
-	if len(clean) == 0 {
-		return "", false
-	}
-
-	// verified = true ako je neki PTR poznat
-	verified := false
-	for _, p := range clean {
-		if _, ok := botdetector.Match(p); ok {
-			verified = true
-			break
-		}
-	}
-
-	trimmed := stripNumericPrefix(clean[0])
-	return baseDomain(trimmed), verified
-}
-
-// WriteResultsCSV writes verification results to CSV (verified as "1" or "0").
-func WriteResultsCSV(outPath string, results []Result) error {
-	f, err := os.Create(outPath)
+// WriteResultsCSV writes verification results to CSV (verified as "1" or "0"),
+// plus the forward-confirmed hostname, the reason code and the method for each verdict.
+func WriteResultsCSV(outPath string, results []Result) (err error) {
+	// iox: "-" = stdout, .gz/.zst kompresija
+	f, err := iox.CreateAuto(outPath)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = f.Close() }()
+	defer func() {
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("close output: %w", cerr)
+		}
+	}()
 
 	w := csv.NewWriter(f)
-	defer w.Flush()
 
-	if err := w.Write([]string{"host_ip", "botName", "verified"}); err != nil {
+	if err := w.Write([]string{"host_ip", "botName", "verified", "hostname", "reason", "method"}); err != nil {
 		return err
 	}
 	for _, r := range results {
@@ -170,9 +275,10 @@ func WriteResultsCSV(outPath string, results []Result) error {
 		if r.Verified {
 			flag = "1"
 		}
-		if err := w.Write([]string{r.IP, r.BotName, flag}); err != nil {
+		if err := w.Write([]string{r.IP, r.BotName, flag, r.Hostname, r.Reason, r.Method}); err != nil {
 			return err
 		}
 	}
+	w.Flush()
 	return w.Error()
 }
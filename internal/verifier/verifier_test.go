@@ -0,0 +1,159 @@
+package verifier
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"parser/internal/botdetector"
+)
+
+const testBots = `
+- name: Googlebot
+  ua: [googlebot]
+  ptr_suffixes: [googlebot.com, google.com]
+- name: Bingbot
+  ua: [bingbot]
+  ptr_suffixes: [search.msn.com]
+  cidr_files: [bing.txt]
+`
+
+const testFake = `
+ptr:
+  66.249.66.1: [crawl-66-249-66-1.googlebot.com]
+  66.249.66.2: [crawl-66-249-66-2.googlebot.com.]
+  66.249.66.3: [crawl-66-249-66-3.googlebot.com]
+  66.249.66.4: [crawl-66-249-66-4.googlebot.com]
+  66.249.66.5: [crawl-66-249-66-5.googlebot.com]
+  66.249.66.6: [crawl.googlebot.com.evil.net, crawl-66-249-66-6.googlebot.com]
+  "2001:db8:0::1": [crawl-2001-db8--1.googlebot.com]
+  1.2.3.4: [crawl.googlebot.com.evil.net]
+  157.55.39.1: [msnbot-157-55-39-1.search.msn.com]
+hosts:
+  crawl-66-249-66-1.googlebot.com: [66.249.66.1]
+  CRAWL-66-249-66-2.googlebot.com.: [66.249.66.2]
+  crawl-66-249-66-3.googlebot.com: [9.9.9.9]
+  crawl-66-249-66-6.googlebot.com: [66.249.66.6]
+  crawl-2001-db8--1.googlebot.com: ["2001:db8::1"]
+  crawl.googlebot.com.evil.net: [1.2.3.4]
+fail: [203.0.113.9, crawl-66-249-66-5.googlebot.com]
+`
+
+// setup: bots pravila sa CIDR listom za Bingbot i FakeResolver iz fajla.
+func setup(t *testing.T) Resolver {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"bots.yaml": testBots,
+		"bing.txt":  "157.55.39.0/24\n",
+		"fake.yaml": testFake,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := botdetector.InitFromFile(filepath.Join(dir, "bots.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { botdetector.InitFromFile("") })
+	r, err := OpenResolver("", filepath.Join(dir, "fake.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestVerifyIPs(t *testing.T) {
+	r := setup(t)
+	cases := []struct {
+		name string
+		want Result
+	}{
+		{"potvrđen", Result{IP: "66.249.66.1", BotName: "Googlebot", Verified: true,
+			Hostname: "crawl-66-249-66-1.googlebot.com", Reason: ReasonConfirmed, Method: MethodDNS}},
+		{"tačka na kraju i veliko slovo", Result{IP: "66.249.66.2", BotName: "Googlebot", Verified: true,
+			Hostname: "crawl-66-249-66-2.googlebot.com", Reason: ReasonConfirmed, Method: MethodDNS}},
+		{"drugi PTR potvrđuje", Result{IP: "66.249.66.6", BotName: "Googlebot", Verified: true,
+			Hostname: "crawl-66-249-66-6.googlebot.com", Reason: ReasonConfirmed, Method: MethodDNS}},
+		{"IPv6", Result{IP: "2001:db8::1", BotName: "Googlebot", Verified: true,
+			Hostname: "crawl-2001-db8--1.googlebot.com", Reason: ReasonConfirmed, Method: MethodDNS}},
+		{"bez PTR-a", Result{IP: "5.6.7.8", BotName: "unable to verify bot",
+			Reason: ReasonNoPTR, Method: MethodDNS}},
+		{"PTR tuđeg domena", Result{IP: "1.2.3.4", BotName: "evil.net",
+			Reason: ReasonUnknownPTR, Method: MethodDNS}},
+		{"forward vraća drugi IP", Result{IP: "66.249.66.3", BotName: "googlebot.com",
+			Reason: ReasonForwardMismatch, Method: MethodDNS}},
+		{"forward bez odgovora", Result{IP: "66.249.66.4", BotName: "googlebot.com",
+			Reason: ReasonForwardMismatch, Method: MethodDNS}},
+		{"PTR lookup greška", Result{IP: "203.0.113.9", BotName: "unable to verify bot",
+			Reason: ReasonLookupError, Method: MethodDNS}},
+		{"forward lookup greška", Result{IP: "66.249.66.5", BotName: "googlebot.com",
+			Reason: ReasonLookupError, Method: MethodDNS}},
+		{"CIDR lista", Result{IP: "157.55.39.1", BotName: "Bingbot", Verified: true,
+			Reason: ReasonCIDRMatch, Method: MethodCIDR}},
+	}
+
+	ips := make([]string, len(cases))
+	for i, tc := range cases {
+		ips[i] = tc.want.IP
+	}
+	progress := make(chan int, len(ips))
+	results, err := VerifyIPs(context.Background(), r, ips, 4, time.Second, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(progress)
+	done := 0
+	for n := range progress {
+		done += n
+	}
+	if len(results) != len(ips) || done != len(ips) {
+		t.Fatalf("results=%d progress=%d, want %d", len(results), done, len(ips))
+	}
+
+	got := make(map[string]Result, len(results))
+	for _, res := range results {
+		got[res.IP] = res
+	}
+	for _, tc := range cases {
+		if g := got[tc.want.IP]; g != tc.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tc.name, g, tc.want)
+		}
+	}
+}
+
+// verified.csv: verified kao 1/0, pa hostname, reason i method.
+func TestWriteResultsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verified.csv")
+	results := []Result{
+		{IP: "66.249.66.1", BotName: "Googlebot", Verified: true, Hostname: "crawl-66-249-66-1.googlebot.com", Reason: ReasonConfirmed, Method: MethodDNS},
+		{IP: "157.55.39.1", BotName: "Bingbot", Verified: true, Reason: ReasonCIDRMatch, Method: MethodCIDR},
+		{IP: "1.2.3.4", BotName: "evil.net", Reason: ReasonUnknownPTR, Method: MethodDNS},
+	}
+	if err := WriteResultsCSV(path, results); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"host_ip", "botName", "verified", "hostname", "reason", "method"},
+		{"66.249.66.1", "Googlebot", "1", "crawl-66-249-66-1.googlebot.com", "fcrdns_ok", "fcrdns"},
+		{"157.55.39.1", "Bingbot", "1", "", "cidr_match", "cidr"},
+		{"1.2.3.4", "evil.net", "0", "", "ptr_unknown", "fcrdns"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("verified.csv:\n got %v\nwant %v", rows, want)
+	}
+}